@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/fennysoftware/vaultviewer/internal/backend"
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	"github.com/fennysoftware/vaultviewer/internal/prompt"
+)
+
+// findIdentity looks an instance up by the name shown in the tree, "name as profile" for profiles
+func findIdentity(vic config.VaultInstanceConfig, name string) (*config.VaultConfig, error) {
+	for _, vconfig := range vic.Instances {
+		for _, identity := range vconfig.Identities() {
+			if backend.NewVaultInstance(identity).DisplayName == name {
+				return identity, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no instance named %q", name)
+}
+
+// connectHeadless logs in and loads the ACL, prompting on the terminal when needed
+func connectHeadless(vic config.VaultInstanceConfig, name string, cache *backend.SessionCache) (backend.VaultInstance, error) {
+	vconfig, err := findIdentity(vic, name)
+	if err != nil {
+		return backend.VaultInstance{}, err
+	}
+	return backend.BuildAndConnect(vconfig, prompt.NewTerminal(), cache, nil)
+}
+
+// runDiff prints what changed in effective access going from left to right
+func runDiff(vic config.VaultInstanceConfig, left string, right string, format string) error {
+	cache, err := backend.NewSessionCache(vic.SessionCache, prompt.NewTerminal())
+	if err != nil {
+		log.Printf("session cache disabled: %v", err)
+	}
+	lvi, err := connectHeadless(vic, left, cache)
+	if err != nil {
+		return err
+	}
+	rvi, err := connectHeadless(vic, right, cache)
+	if err != nil {
+		return err
+	}
+
+	diff := backend.DiffACL(&lvi, &rvi)
+	switch format {
+	case "text":
+		fmt.Print(diff.Text())
+	case "json":
+		out, err := diff.JSON()
+		if err != nil {
+			return err
+		}
+		fmt.Print(out)
+	default:
+		return fmt.Errorf("unknown format %q, use text or json", format)
+	}
+	return nil
+}
+
+// runLint prints the findings for each instance, failed is true when any reaches failOn
+func runLint(vic config.VaultInstanceConfig, names []string, failOn string, format string) (bool, error) {
+	threshold, err := backend.ParseSeverity(failOn)
+	if err != nil {
+		return false, err
+	}
+	if format != "text" && format != "json" {
+		return false, fmt.Errorf("unknown format %q, use text or json", format)
+	}
+	cache, err := backend.NewSessionCache(vic.SessionCache, prompt.NewTerminal())
+	if err != nil {
+		log.Printf("session cache disabled: %v", err)
+	}
+
+	failed := false
+	report := map[string][]backend.Finding{}
+	for _, name := range names {
+		vi, err := connectHeadless(vic, name, cache)
+		if err != nil {
+			return false, err
+		}
+		if vi.PoliciesErr != nil {
+			log.Printf("%s: only the resultant ACL is linted: %v", name, vi.PoliciesErr)
+		}
+		findings := backend.Lint(vi.Policies, vi.Acl, vic.Lint)
+		for _, f := range findings {
+			if f.Severity >= threshold {
+				failed = true
+			}
+		}
+		report[name] = findings
+		if format == "text" {
+			fmt.Print(backend.FindingsText(name, findings))
+		}
+	}
+
+	if format == "json" {
+		data, err := json.MarshalIndent(report, "", "\t")
+		if err != nil {
+			return false, err
+		}
+		fmt.Println(string(data))
+	}
+	return failed, nil
+}
@@ -0,0 +1,252 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ACLParseError is a value in the resultant ACL that doesn't have the expected shape
+type ACLParseError struct {
+	Path     string
+	Key      string
+	Expected string
+	Got      interface{}
+}
+
+func (e *ACLParseError) Error() string {
+	if len(e.Path) == 0 {
+		return fmt.Sprintf("resultant ACL %s: expected %s, got %T", e.Key, e.Expected, e.Got)
+	}
+	return fmt.Sprintf("resultant ACL %s %s: expected %s, got %T", e.Path, e.Key, e.Expected, e.Got)
+}
+
+// toPathPermissions parses one of the path maps of the resultant ACL.
+// Paths or keys that don't parse are reported and skipped.
+func toPathPermissions(pt map[string]interface{}) ([]PathPermissions, []error) {
+
+	pathps := []PathPermissions{}
+	errs := []error{}
+
+	for k, v1 := range pt {
+		pp := PathPermissions{}
+		pp.Path = k
+
+		pt1, ok := v1.(map[string]interface{})
+		if !ok {
+			errs = append(errs, &ACLParseError{Path: k, Expected: "object", Got: v1})
+			continue
+		}
+
+		perms, perrs := toACLPermissions(k, pt1)
+		errs = append(errs, perrs...)
+		pp.Permissions = perms
+		pathps = append(pathps, pp)
+	}
+
+	sort.Sort(ppSort(pathps))
+
+	return pathps, errs
+}
+
+func toACLPermissions(path string, pt map[string]interface{}) (*ACLPermissions, []error) {
+	errs := []error{}
+	perr := func(key string, expected string, got interface{}) {
+		errs = append(errs, &ACLParseError{Path: path, Key: key, Expected: expected, Got: got})
+	}
+
+	perms := &ACLPermissions{}
+	perms.MinWrappingTTL = 0
+	perms.MaxWrappingTTL = 0
+	perms.AllowedParameters = map[string][]interface{}{}
+	perms.DeniedParameters = map[string][]interface{}{}
+	perms.RequiredParameters = []string{}
+	perms.Capabilities = []string{}
+
+	for k, v := range pt {
+		switch k {
+		case "capabilities":
+			capabilities, ok := toStrings(v)
+			if !ok {
+				perr(k, "list of strings", v)
+				continue
+			}
+			res := uint32(0)
+			for _, capability := range capabilities {
+				if capability != "" {
+					res |= cap2Int[capability]
+					perms.Capabilities = append(perms.Capabilities, capability)
+				}
+			}
+			if res == 0 {
+				perms.CapabilitiesBitmap = DenyCapabilityInt
+			} else {
+				perms.CapabilitiesBitmap = res
+			}
+		case "allowed_parameters", "denied_parameters":
+			params, ok := toParameters(v)
+			if !ok {
+				perr(k, "map of parameter values", v)
+				continue
+			}
+			if k == "allowed_parameters" {
+				perms.AllowedParameters = params
+			} else {
+				perms.DeniedParameters = params
+			}
+		case "required_parameters":
+			required, ok := toStrings(v)
+			if !ok {
+				perr(k, "list of strings", v)
+				continue
+			}
+			perms.RequiredParameters = required
+		case "min_wrapping_ttl", "max_wrapping_ttl":
+			ttl, ok := toDuration(v)
+			if !ok {
+				perr(k, "duration", v)
+				continue
+			}
+			if k == "min_wrapping_ttl" {
+				perms.MinWrappingTTL = ttl
+			} else {
+				perms.MaxWrappingTTL = ttl
+			}
+		case "mfa_methods":
+			methods, ok := toStrings(v)
+			if !ok {
+				perr(k, "list of strings", v)
+				continue
+			}
+			perms.MFAMethods = methods
+		case "control_group":
+			cg, err := toControlGroup(path, v)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			perms.ControlGroup = cg
+		}
+	}
+	return perms, errs
+}
+
+func toControlGroup(path string, v interface{}) (*ControlGroup, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, &ACLParseError{Path: path, Key: "control_group", Expected: "object", Got: v}
+	}
+	cg := &ControlGroup{}
+	if ttl, ok := m["ttl"]; ok {
+		cg.TTL, ok = toDuration(ttl)
+		if !ok {
+			return nil, &ACLParseError{Path: path, Key: "control_group.ttl", Expected: "duration", Got: ttl}
+		}
+	}
+
+	factors := []interface{}{}
+	switch f := m["factors"].(type) {
+	case nil:
+	case []interface{}:
+		factors = f
+	case map[string]interface{}:
+		// policies write factors as named blocks
+		names := []string{}
+		for name := range f {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if fm, ok := f[name].(map[string]interface{}); ok {
+				fm["name"] = name
+			}
+			factors = append(factors, f[name])
+		}
+	default:
+		return nil, &ACLParseError{Path: path, Key: "control_group.factors", Expected: "list of factors", Got: f}
+	}
+
+	for _, fv := range factors {
+		fm, ok := fv.(map[string]interface{})
+		if !ok {
+			return nil, &ACLParseError{Path: path, Key: "control_group.factors", Expected: "object", Got: fv}
+		}
+		factor := &ControlGroupFactor{}
+		factor.Name, _ = fm["name"].(string)
+		factor.ControlledCapabilities, _ = toStrings(fm["controlled_capabilities"])
+		if id, ok := fm["identity"].(map[string]interface{}); ok {
+			factor.Identity = &IdentityFactor{}
+			factor.Identity.GroupIDs, _ = toStrings(id["group_ids"])
+			factor.Identity.GroupNames, _ = toStrings(id["group_names"])
+			if approvals, ok := id["approvals"].(json.Number); ok {
+				n, _ := approvals.Int64()
+				factor.Identity.ApprovalsRequired = int(n)
+			}
+		}
+		cg.Factors = append(cg.Factors, factor)
+	}
+	return cg, nil
+}
+
+func toStrings(v interface{}) ([]string, bool) {
+	if v == nil {
+		return []string{}, true
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	out := []string{}
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	return out, true
+}
+
+func toParameters(v interface{}) (map[string][]interface{}, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	params := map[string][]interface{}{}
+	for k, pv := range m {
+		switch values := pv.(type) {
+		case nil:
+			params[k] = []interface{}{}
+		case []interface{}:
+			params[k] = values
+		default:
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// toDuration takes numbers as seconds and strings as Go or Vault style durations
+func toDuration(v interface{}) (time.Duration, bool) {
+	switch d := v.(type) {
+	case json.Number:
+		seconds, err := d.Float64()
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(seconds * float64(time.Second)), true
+	case float64:
+		return time.Duration(d * float64(time.Second)), true
+	case int:
+		return time.Duration(d) * time.Second, true
+	case string:
+		if parsed, err := time.ParseDuration(d); err == nil {
+			return parsed, true
+		}
+		if seconds, err := json.Number(d).Int64(); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	return 0, false
+}
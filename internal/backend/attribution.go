@@ -0,0 +1,86 @@
+package backend
+
+import (
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// grantedBy records the policy as the source of every capability of a rule, a deny is kept
+// under DenyCapabilityInt
+func (perms *ACLPermissions) grantedBy(p *Policy) {
+	perms.GrantingPoliciesMap = map[uint32][]logical.PolicyInfo{}
+	info := logical.PolicyInfo{Name: p.Name, Type: p.Type.String()}
+	for _, c := range capabilityOrder {
+		if perms.CapabilitiesBitmap&c.bit != 0 {
+			perms.GrantingPoliciesMap[c.bit] = []logical.PolicyInfo{info}
+		}
+	}
+}
+
+// ruleKey is how a rule is written in a policy, prefix rules end in *
+func ruleKey(path string, isPrefix bool) string {
+	path = strings.TrimSuffix(path, "*")
+	if isPrefix {
+		return path + "*"
+	}
+	return path
+}
+
+// AttributePolicies fills GrantingPoliciesMap on the resultant ACL from the parsed rules of the
+// policies attached to the token. Policies that couldn't be read leave gaps in the attribution.
+func (vi *VaultInstance) AttributePolicies() {
+	rules := map[string][]*PathRules{}
+	for _, p := range vi.Policies {
+		if !vi.InEffect(p.Name) {
+			continue
+		}
+		for _, pr := range p.Paths {
+			// templated rules show up in the resultant ACL with the placeholders filled in
+			path, unresolved := vi.ResolveRule(pr)
+			if len(unresolved) > 0 {
+				continue
+			}
+			rules[path] = append(rules[path], pr)
+		}
+	}
+
+	attribute := func(pp PathPermissions, isPrefix bool) {
+		if pp.Permissions == nil {
+			return
+		}
+		granting := map[uint32][]logical.PolicyInfo{}
+		for _, pr := range rules[ruleKey(pp.Path, isPrefix)] {
+			for bit, infos := range pr.Permissions.GrantingPoliciesMap {
+				granting[bit] = append(granting[bit], infos...)
+			}
+		}
+		pp.Permissions.GrantingPoliciesMap = granting
+	}
+	for _, pp := range vi.Acl.ExactRules {
+		attribute(pp, false)
+	}
+	for _, pp := range vi.Acl.PrefixRules {
+		attribute(pp, true)
+	}
+	for _, pp := range vi.Acl.SegmentWildcardRules {
+		attribute(pp, strings.HasSuffix(pp.Path, "*"))
+	}
+}
+
+// GrantingPolicies names the policies that give the capability on the rule
+func (perms *ACLPermissions) GrantingPolicies(capability string) []string {
+	names := []string{}
+	if perms == nil {
+		return names
+	}
+	for _, info := range perms.GrantingPoliciesMap[cap2Int[capability]] {
+		names = append(names, info.Name)
+	}
+	return names
+}
+
+// DenyingPolicies names the policies that deny the rule's path
+func (perms *ACLPermissions) DenyingPolicies() []string {
+	return perms.GrantingPolicies(DenyCapability)
+}
@@ -0,0 +1,133 @@
+package backend
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	"github.com/hashicorp/vault/api"
+	"gopkg.in/yaml.v3"
+)
+
+// Authenticator logs in to Vault with one auth method
+type Authenticator interface {
+	// MountPath is where the auth method is mounted, without the auth/ prefix
+	MountPath() string
+	// Login returns the secret holding the client token
+	Login(vi VaultInstance) (*api.Secret, error)
+}
+
+// tokenReuser is implemented by authenticators that may hand back a token that already
+// existed, e.g. from VAULT_TOKEN, rather than one issued by a login
+type tokenReuser interface {
+	reused() bool
+}
+
+// AuthFactory builds an Authenticator from the method's block in the auth config.
+// block is nil when the method is used without any config.
+type AuthFactory func(block *yaml.Node) (Authenticator, error)
+
+var authRegistry = map[string]AuthFactory{}
+
+// RegisterAuthenticator makes an auth method available under its config key
+func RegisterAuthenticator(method string, factory AuthFactory) {
+	authRegistry[method] = factory
+}
+
+// AuthMethods lists the registered method names
+func AuthMethods() []string {
+	methods := []string{}
+	for k := range authRegistry {
+		methods = append(methods, k)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// NewAuthenticator picks the single configured method and builds its authenticator.
+// With no auth config at all the vault CLI token is used, falling back to prompting for userpass credentials.
+func NewAuthenticator(a *config.VaultAuth) (string, Authenticator, error) {
+	if a == nil {
+		fallback, err := authRegistry["userpass"](nil)
+		if err != nil {
+			return "", nil, err
+		}
+		return "tokenHelper", &tokenHelperAuthenticator{fallback: fallback}, nil
+	}
+
+	configured := []string{}
+	for k := range a.Methods {
+		configured = append(configured, k)
+	}
+	sort.Strings(configured)
+
+	if len(configured) == 0 {
+		return "", nil, fmt.Errorf("no auth method configured, expected one of: %s", strings.Join(AuthMethods(), ", "))
+	}
+	if len(configured) > 1 {
+		return "", nil, fmt.Errorf("multiple auth methods configured (%s), only one is allowed", strings.Join(configured, ", "))
+	}
+
+	method := configured[0]
+	factory, ok := authRegistry[method]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown auth method %q, expected one of: %s", method, strings.Join(AuthMethods(), ", "))
+	}
+	auth, err := factory(a.Methods[method])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid %s auth config: %w", method, err)
+	}
+	return method, auth, nil
+}
+
+// decodeBlock decodes a method block into out, a nil block leaves out untouched
+func decodeBlock(block *yaml.Node, out interface{}) error {
+	if block == nil {
+		return nil
+	}
+	return block.Decode(out)
+}
+
+func mountPathOr(mp string, def string) string {
+	mp = strings.Trim(mp, "/")
+	if len(mp) == 0 {
+		return def
+	}
+	return mp
+}
+
+// readSecret takes a value from the file or environment variable when set, otherwise the literal
+func readSecret(literal string, file string, env string) (string, error) {
+	if file != "" {
+		return readPasswordFromFile(file)
+	}
+	if env != "" {
+		value := os.Getenv(env)
+		if value == "" {
+			return "", fmt.Errorf("environment variable %s is empty", env)
+		}
+		return value, nil
+	}
+	return literal, nil
+}
+
+func readPasswordFromFile(path string) (string, error) {
+	passwordFile, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to open file containing password: %w", err)
+	}
+	defer passwordFile.Close()
+
+	limitedReader := io.LimitReader(passwordFile, 1000)
+	passwordBytes, err := io.ReadAll(limitedReader)
+	if err != nil {
+		return "", fmt.Errorf("unable to read password: %w", err)
+	}
+
+	passwordValue := strings.TrimSuffix(string(passwordBytes), "\n")
+
+	return passwordValue, nil
+}
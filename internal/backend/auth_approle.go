@@ -0,0 +1,73 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	"github.com/hashicorp/vault/api"
+	"gopkg.in/yaml.v3"
+)
+
+type approleAuthenticator struct {
+	config config.AppRoleAuth
+}
+
+func init() {
+	RegisterAuthenticator("approle", newAppRoleAuthenticator)
+}
+
+func newAppRoleAuthenticator(block *yaml.Node) (Authenticator, error) {
+	aa := &approleAuthenticator{}
+	if err := decodeBlock(block, &aa.config); err != nil {
+		return nil, err
+	}
+	if len(aa.config.RoleID) == 0 && len(aa.config.RoleIDFile) == 0 {
+		return nil, fmt.Errorf("roleId or roleIdFile is required")
+	}
+	return aa, nil
+}
+
+func (aa *approleAuthenticator) MountPath() string {
+	return mountPathOr(aa.config.MountPath, "approle")
+}
+
+func (aa *approleAuthenticator) Login(vi VaultInstance) (*api.Secret, error) {
+	roleID, err := readSecret(aa.config.RoleID, aa.config.RoleIDFile, "")
+	if err != nil {
+		return nil, fmt.Errorf("error reading role id: %w", err)
+	}
+	secretID, err := readSecret(aa.config.SecretID, aa.config.SecretIDFile, aa.config.SecretIDEnv)
+	if err != nil {
+		return nil, fmt.Errorf("error reading secret id: %w", err)
+	}
+
+	if aa.config.SecretIDWrapped {
+		if len(secretID) == 0 {
+			return nil, fmt.Errorf("secretIdWrapped is set but no wrapped secret id was given")
+		}
+		unwrapped, err := vi.unwrap(secretID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to unwrap secret id: %w", err)
+		}
+		sid, ok := unwrapped.Data["secret_id"].(string)
+		if !ok || len(sid) == 0 {
+			return nil, fmt.Errorf("unwrapped response has no secret_id")
+		}
+		secretID = sid
+	}
+
+	options := map[string]interface{}{
+		"role_id": strings.TrimSpace(roleID),
+	}
+	// the role may be configured without bind_secret_id
+	if len(secretID) > 0 {
+		options["secret_id"] = strings.TrimSpace(secretID)
+	}
+
+	login, err := vi.Client.Logical().Write(fmt.Sprintf("auth/%s/login", aa.MountPath()), options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to log in with AppRole auth: %w", err)
+	}
+	return login, nil
+}
@@ -0,0 +1,128 @@
+package backend
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+)
+
+func TestAppRoleLogin(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "secret-id")
+	if err := os.WriteFile(secretFile, []byte("secret-from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VV_TEST_SECRET_ID", "secret-from-env")
+
+	tests := []struct {
+		name     string
+		config   config.AppRoleAuth
+		wantPath string
+		wantSID  string
+	}{
+		{
+			name:     "literal",
+			config:   config.AppRoleAuth{RoleID: "role", SecretID: "secret-literal"},
+			wantPath: "auth/approle/login",
+			wantSID:  "secret-literal",
+		},
+		{
+			name:     "file",
+			config:   config.AppRoleAuth{RoleID: "role", SecretIDFile: secretFile},
+			wantPath: "auth/approle/login",
+			wantSID:  "secret-from-file",
+		},
+		{
+			name:     "env",
+			config:   config.AppRoleAuth{RoleID: "role", SecretIDEnv: "VV_TEST_SECRET_ID"},
+			wantPath: "auth/approle/login",
+			wantSID:  "secret-from-env",
+		},
+		{
+			name:     "wrapped",
+			config:   config.AppRoleAuth{MountPath: "/machines/", RoleID: "role", SecretID: "wrapping-token", SecretIDWrapped: true},
+			wantPath: "auth/machines/login",
+			wantSID:  "secret-unwrapped",
+		},
+		{
+			name:     "no secret id",
+			config:   config.AppRoleAuth{RoleID: "role"},
+			wantPath: "auth/approle/login",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fv := newFakeVault(t)
+			fv.reply(tt.wantPath, loginResponse("s.approle"))
+			fv.handle("sys/wrapping/unwrap", func(r fakeRequest) (int, interface{}) {
+				if r.Token != "wrapping-token" {
+					return http.StatusBadRequest, map[string]interface{}{"errors": []string{"wrong token"}}
+				}
+				return http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"secret_id": "secret-unwrapped"}}
+			})
+
+			aa := &approleAuthenticator{config: tt.config}
+			login, err := aa.Login(fv.instance(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := clientToken(t, login); got != "s.approle" {
+				t.Errorf("client token = %q", got)
+			}
+
+			reqs := fv.received(tt.wantPath)
+			if len(reqs) != 1 {
+				t.Fatalf("%d requests to %s, want 1", len(reqs), tt.wantPath)
+			}
+			if reqs[0].Body["role_id"] != "role" {
+				t.Errorf("role_id = %v", reqs[0].Body["role_id"])
+			}
+			sid, sent := reqs[0].Body["secret_id"]
+			if len(tt.wantSID) == 0 {
+				if sent {
+					t.Errorf("secret_id %v sent without one configured", sid)
+				}
+			} else if sid != tt.wantSID {
+				t.Errorf("secret_id = %v, want %s", sid, tt.wantSID)
+			}
+
+			unwraps := len(fv.received("sys/wrapping/unwrap"))
+			if tt.config.SecretIDWrapped != (unwraps == 1) {
+				t.Errorf("%d unwrap requests", unwraps)
+			}
+		})
+	}
+}
+
+func TestAppRoleLoginErrors(t *testing.T) {
+	fv := newFakeVault(t)
+	fv.handle("sys/wrapping/unwrap", func(fakeRequest) (int, interface{}) {
+		return http.StatusBadRequest, map[string]interface{}{"errors": []string{"wrapping token is not valid or does not exist"}}
+	})
+	fv.handle("auth/approle/login", func(fakeRequest) (int, interface{}) {
+		return http.StatusBadRequest, map[string]interface{}{"errors": []string{"invalid secret id"}}
+	})
+
+	tests := []struct {
+		name   string
+		config config.AppRoleAuth
+	}{
+		{"missing file", config.AppRoleAuth{RoleID: "role", SecretIDFile: filepath.Join(t.TempDir(), "missing")}},
+		{"empty env", config.AppRoleAuth{RoleID: "role", SecretIDEnv: "VV_TEST_UNSET_SECRET_ID"}},
+		{"wrapped without token", config.AppRoleAuth{RoleID: "role", SecretIDWrapped: true}},
+		{"bad wrapping token", config.AppRoleAuth{RoleID: "role", SecretID: "expired", SecretIDWrapped: true}},
+		{"login refused", config.AppRoleAuth{RoleID: "role", SecretID: "secret"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aa := &approleAuthenticator{config: tt.config}
+			if _, err := aa.Login(fv.instance(t)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
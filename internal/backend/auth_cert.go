@@ -0,0 +1,47 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	"github.com/hashicorp/vault/api"
+	"gopkg.in/yaml.v3"
+)
+
+type certAuthenticator struct {
+	config config.CertAuth
+}
+
+func init() {
+	RegisterAuthenticator("cert", newCertAuthenticator)
+}
+
+func newCertAuthenticator(block *yaml.Node) (Authenticator, error) {
+	ca := &certAuthenticator{}
+	if err := decodeBlock(block, &ca.config); err != nil {
+		return nil, err
+	}
+	return ca, nil
+}
+
+func (ca *certAuthenticator) MountPath() string {
+	return mountPathOr(ca.config.MountPath, "cert")
+}
+
+// the client certificate comes from the instance tls settings and is presented on the connection
+func (ca *certAuthenticator) Login(vi VaultInstance) (*api.Secret, error) {
+	if vi.Config == nil || vi.Config.TLS == nil || len(vi.Config.TLS.ClientCert) == 0 || len(vi.Config.TLS.ClientKey) == 0 {
+		return nil, fmt.Errorf("cert auth needs tls.clientCert and tls.clientKey on the instance")
+	}
+
+	options := map[string]interface{}{}
+	if len(ca.config.Name) > 0 {
+		options["name"] = ca.config.Name
+	}
+
+	login, err := vi.Client.Logical().Write(fmt.Sprintf("auth/%s/login", ca.MountPath()), options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to log in with cert auth: %w", err)
+	}
+	return login, nil
+}
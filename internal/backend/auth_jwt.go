@@ -0,0 +1,50 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	"github.com/hashicorp/vault/api"
+	"gopkg.in/yaml.v3"
+)
+
+type jwtAuthenticator struct {
+	config config.JWTAuth
+}
+
+func init() {
+	RegisterAuthenticator("jwt", newJWTAuthenticator)
+}
+
+// jwt accepts either `jwt: <jwt>` or a block with jwt, role and mountPath
+func newJWTAuthenticator(block *yaml.Node) (Authenticator, error) {
+	ja := &jwtAuthenticator{}
+	if block != nil && block.Kind == yaml.ScalarNode {
+		ja.config.JWT = block.Value
+	} else if err := decodeBlock(block, &ja.config); err != nil {
+		return nil, err
+	}
+	if len(ja.config.JWT) == 0 {
+		return nil, fmt.Errorf("jwt is empty")
+	}
+	return ja, nil
+}
+
+func (ja *jwtAuthenticator) MountPath() string {
+	return mountPathOr(ja.config.MountPath, "jwt")
+}
+
+func (ja *jwtAuthenticator) Login(vi VaultInstance) (*api.Secret, error) {
+	options := map[string]interface{}{
+		"jwt": ja.config.JWT,
+	}
+	if len(ja.config.Role) > 0 {
+		options["role"] = ja.config.Role
+	}
+	// PUT call to get a token
+	login, err := vi.Client.Logical().Write(fmt.Sprintf("auth/%s/login", ja.MountPath()), options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to log in with JWT auth: %w", err)
+	}
+	return login, nil
+}
@@ -0,0 +1,58 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	"github.com/hashicorp/vault/api"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+type kubernetesAuthenticator struct {
+	config config.KubernetesAuth
+}
+
+func init() {
+	RegisterAuthenticator("kubernetes", newKubernetesAuthenticator)
+}
+
+func newKubernetesAuthenticator(block *yaml.Node) (Authenticator, error) {
+	ka := &kubernetesAuthenticator{}
+	if err := decodeBlock(block, &ka.config); err != nil {
+		return nil, err
+	}
+	if len(ka.config.Role) == 0 {
+		return nil, fmt.Errorf("role is required")
+	}
+	if len(ka.config.TokenPath) == 0 {
+		ka.config.TokenPath = defaultServiceAccountTokenPath
+	}
+	return ka, nil
+}
+
+func (ka *kubernetesAuthenticator) MountPath() string {
+	return mountPathOr(ka.config.MountPath, "kubernetes")
+}
+
+// the token file is read on every login so rotated projected tokens are picked up
+func (ka *kubernetesAuthenticator) Login(vi VaultInstance) (*api.Secret, error) {
+	jwt, err := os.ReadFile(ka.config.TokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read service account token: %w", err)
+	}
+
+	options := map[string]interface{}{
+		"role": ka.config.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	}
+
+	login, err := vi.Client.Logical().Write(fmt.Sprintf("auth/%s/login", ka.MountPath()), options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to log in with Kubernetes auth: %w", err)
+	}
+	return login, nil
+}
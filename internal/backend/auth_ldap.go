@@ -0,0 +1,75 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	"github.com/fennysoftware/vaultviewer/internal/prompt"
+	"github.com/hashicorp/vault/api"
+	"gopkg.in/yaml.v3"
+)
+
+type ldapAuthenticator struct {
+	config config.LDAPAuth
+}
+
+func init() {
+	RegisterAuthenticator("ldap", newLDAPAuthenticator)
+}
+
+func newLDAPAuthenticator(block *yaml.Node) (Authenticator, error) {
+	la := &ldapAuthenticator{}
+	if err := decodeBlock(block, &la.config); err != nil {
+		return nil, err
+	}
+	// only the interactive login asks for the username
+	if len(la.config.Username) == 0 && (len(la.config.PasswordFile) > 0 || len(la.config.PasswordEnv) > 0) {
+		return nil, fmt.Errorf("username is required with passwordFile or passwordEnv")
+	}
+	return la, nil
+}
+
+func (la *ldapAuthenticator) MountPath() string {
+	return mountPathOr(la.config.MountPath, "ldap")
+}
+
+func (la *ldapAuthenticator) Login(vi VaultInstance) (*api.Secret, error) {
+	a := la.config
+	loginData := make(map[string]interface{})
+	username := a.Username
+
+	if a.PasswordFile != "" {
+		passwordValue, err := readPasswordFromFile(a.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading password: %w", err)
+		}
+		loginData["password"] = passwordValue
+	} else if a.PasswordEnv != "" {
+		passwordValue := os.Getenv(a.PasswordEnv)
+		if passwordValue == "" {
+			return nil, fmt.Errorf("password was specified with an environment variable with an empty value")
+		}
+		loginData["password"] = passwordValue
+	} else {
+		pwd := a.Password
+		if len(pwd) == 0 || len(username) == 0 {
+			var err error
+			username, pwd, err = prompt.Credentials(vi.prompter(), vi.DisplayName+" LDAP login", username)
+			if err != nil {
+				return nil, err
+			}
+		}
+		loginData["password"] = pwd
+	}
+
+	path := fmt.Sprintf("auth/%s/login/%s", la.MountPath(), username)
+	ctx := context.Background()
+
+	login, err := vi.Client.Logical().WriteWithContext(ctx, path, loginData)
+	if err != nil {
+		return nil, fmt.Errorf("unable to log in with LDAP auth: %w", err)
+	}
+	return login, nil
+}
@@ -0,0 +1,40 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func TestLDAPConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		block   string
+		wantErr string
+	}{
+		{name: "interactive", block: "ldap: {}"},
+		{name: "password file", block: "ldap:\n  username: alice\n  passwordFile: /run/pw"},
+		{name: "password file without username", block: "ldap:\n  passwordFile: /run/pw", wantErr: "username is required"},
+		{name: "password env without username", block: "ldap:\n  passwordEnv: LDAP_PW", wantErr: "username is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &config.VaultAuth{}
+			if err := yaml.Unmarshal([]byte(tt.block), a); err != nil {
+				t.Fatal(err)
+			}
+			_, _, err := NewAuthenticator(a)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package backend
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	"github.com/fennysoftware/vaultviewer/internal/prompt"
+	"github.com/hashicorp/vault/api"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultOIDCCallbackHost = "localhost"
+	defaultOIDCCallbackPort = 8250
+	defaultOIDCTimeout      = 2 * time.Minute
+)
+
+type oidcAuthenticator struct {
+	config  config.OIDCAuth
+	timeout time.Duration
+}
+
+type oidcCallback struct {
+	code    string
+	idToken string
+	err     error
+}
+
+func init() {
+	RegisterAuthenticator("oidc", newOIDCAuthenticator)
+}
+
+func newOIDCAuthenticator(block *yaml.Node) (Authenticator, error) {
+	oa := &oidcAuthenticator{timeout: defaultOIDCTimeout}
+	if err := decodeBlock(block, &oa.config); err != nil {
+		return nil, err
+	}
+	if len(oa.config.CallbackHost) == 0 {
+		oa.config.CallbackHost = defaultOIDCCallbackHost
+	}
+	if oa.config.CallbackPort == 0 {
+		oa.config.CallbackPort = defaultOIDCCallbackPort
+	}
+	if len(oa.config.Timeout) > 0 {
+		d, err := time.ParseDuration(oa.config.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		oa.timeout = d
+	}
+	return oa, nil
+}
+
+func (oa *oidcAuthenticator) MountPath() string {
+	return mountPathOr(oa.config.MountPath, "oidc")
+}
+
+func (oa *oidcAuthenticator) redirectURI() string {
+	return fmt.Sprintf("http://%s/oidc/callback", net.JoinHostPort(oa.config.CallbackHost, strconv.Itoa(oa.config.CallbackPort)))
+}
+
+// Login asks Vault for the provider URL, waits for the browser to come back to the
+// local listener and exchanges the code for a client token
+func (oa *oidcAuthenticator) Login(vi VaultInstance) (*api.Secret, error) {
+	clientNonce, err := randomHex(20)
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, state, err := oa.authURL(vi, clientNonce)
+	if err != nil {
+		return nil, err
+	}
+
+	// listen before handing out the URL so a fast provider can't beat us
+	listener, err := net.Listen("tcp", net.JoinHostPort(oa.config.CallbackHost, strconv.Itoa(oa.config.CallbackPort)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to start OIDC callback listener: %w", err)
+	}
+
+	callbacks := make(chan oidcCallback, 1)
+	server := &http.Server{Handler: oidcCallbackHandler(state, callbacks)}
+	go server.Serve(listener)
+	defer server.Close()
+
+	prompt.Notify(vi.prompter(), vi.DisplayName+" OIDC login", "Complete the login in your browser:\n\n"+authURL)
+	if !oa.config.SkipBrowser {
+		if err := openBrowser(authURL); err != nil {
+			log.Printf("unable to open browser: %v", err)
+		}
+	}
+
+	var cb oidcCallback
+	select {
+	case cb = <-callbacks:
+	case <-time.After(oa.timeout):
+		return nil, fmt.Errorf("timed out after %s waiting for the OIDC callback", oa.timeout)
+	}
+	if cb.err != nil {
+		return nil, cb.err
+	}
+
+	data := map[string][]string{
+		"state":        {state},
+		"code":         {cb.code},
+		"client_nonce": {clientNonce},
+	}
+	if len(cb.idToken) > 0 {
+		data["id_token"] = []string{cb.idToken}
+	}
+	login, err := vi.Client.Logical().ReadWithDataWithContext(context.Background(), fmt.Sprintf("auth/%s/oidc/callback", oa.MountPath()), data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to complete OIDC login: %w", err)
+	}
+	return login, nil
+}
+
+// authURL returns the provider URL and the state Vault put in it
+func (oa *oidcAuthenticator) authURL(vi VaultInstance, clientNonce string) (string, string, error) {
+	options := map[string]interface{}{
+		"role":         oa.config.Role,
+		"redirect_uri": oa.redirectURI(),
+		"client_nonce": clientNonce,
+	}
+	secret, err := vi.Client.Logical().Write(fmt.Sprintf("auth/%s/oidc/auth_url", oa.MountPath()), options)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to get OIDC auth url: %w", err)
+	}
+	if secret == nil {
+		return "", "", fmt.Errorf("no OIDC auth url returned")
+	}
+	authURL, _ := secret.Data["auth_url"].(string)
+	if len(authURL) == 0 {
+		return "", "", fmt.Errorf("no OIDC auth url returned, check the role %q allows %s as a redirect uri", oa.config.Role, oa.redirectURI())
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid OIDC auth url: %w", err)
+	}
+	state := parsed.Query().Get("state")
+	if len(state) == 0 {
+		return "", "", fmt.Errorf("OIDC auth url has no state")
+	}
+	if len(parsed.Query().Get("nonce")) == 0 {
+		return "", "", fmt.Errorf("OIDC auth url has no nonce")
+	}
+	return authURL, state, nil
+}
+
+// oidcCallbackHandler accepts the first callback carrying the expected state
+func oidcCallbackHandler(state string, callbacks chan<- oidcCallback) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oidc/callback", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		cb := oidcCallback{}
+		switch {
+		case q.Get("state") != state:
+			// not ours, don't end the login over it, not even for an error
+			http.Error(w, "state mismatch", http.StatusBadRequest)
+			return
+		case len(q.Get("error")) > 0:
+			cb.err = fmt.Errorf("OIDC provider returned %s: %s", q.Get("error"), q.Get("error_description"))
+		case len(q.Get("code")) == 0:
+			cb.err = errors.New("OIDC callback has no code")
+		default:
+			cb.code = q.Get("code")
+			cb.idToken = q.Get("id_token")
+		}
+
+		if cb.err != nil {
+			http.Error(w, cb.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Login complete, you can close this window and return to vaultviewer.")
+		}
+		select {
+		case callbacks <- cb:
+		default:
+		}
+	})
+	return mux
+}
+
+func openBrowser(u string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", u).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", u).Start()
+	default:
+		return exec.Command("xdg-open", u).Start()
+	}
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
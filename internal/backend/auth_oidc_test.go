@@ -0,0 +1,269 @@
+package backend
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	"github.com/fennysoftware/vaultviewer/internal/prompt"
+)
+
+// browserPrompter stands in for the user, the auth url shown by Notify is handed to browse
+type browserPrompter struct {
+	browse func(authURL string)
+}
+
+func (bp *browserPrompter) Ask(string, []prompt.Field) ([]string, error) {
+	return nil, prompt.ErrCancelled
+}
+
+func (bp *browserPrompter) Notify(title string, message string) {
+	lines := strings.Split(message, "\n")
+	go bp.browse(lines[len(lines)-1])
+}
+
+// fakeIdP redirects straight back to the redirect_uri with a code, the way a provider does
+// for a user that is already signed in
+func fakeIdP(t *testing.T) *httptest.Server {
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		back := q.Get("redirect_uri") + "?" + url.Values{"state": {q.Get("state")}, "code": {"idp-code"}}.Encode()
+		http.Redirect(w, r, back, http.StatusFound)
+	}))
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// oidcFixture is a fake Vault with the OIDC mount and an authenticator listening on a free port
+func oidcFixture(t *testing.T, idp *httptest.Server) (*fakeVault, *oidcAuthenticator) {
+	oa := &oidcAuthenticator{
+		config: config.OIDCAuth{
+			Role:         "dev",
+			CallbackHost: "127.0.0.1",
+			CallbackPort: freePort(t),
+			SkipBrowser:  true,
+		},
+		timeout: 5 * time.Second,
+	}
+
+	fv := newFakeVault(t)
+	fv.handle("auth/oidc/oidc/auth_url", func(r fakeRequest) (int, interface{}) {
+		q := url.Values{
+			"state":        {"vault-state"},
+			"nonce":        {"vault-nonce"},
+			"redirect_uri": {fmt.Sprint(r.Body["redirect_uri"])},
+		}
+		return http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"auth_url": idp.URL + "/authorize?" + q.Encode()}}
+	})
+	fv.reply("auth/oidc/oidc/callback", loginResponse("s.oidc"))
+	return fv, oa
+}
+
+// callbackURL is where the authenticator listens, with the given query
+func (oa *oidcAuthenticator) callbackURL(q url.Values) string {
+	return oa.redirectURI() + "?" + q.Encode()
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := fakeIdP(t)
+	fv, oa := oidcFixture(t, idp)
+
+	vi := fv.instance(t)
+	vi.Prompter = &browserPrompter{browse: func(authURL string) {
+		if _, err := http.Get(authURL); err != nil {
+			t.Error(err)
+		}
+	}}
+	login, err := oa.Login(vi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := clientToken(t, login); got != "s.oidc" {
+		t.Errorf("client token = %q", got)
+	}
+
+	authReqs := fv.received("auth/oidc/oidc/auth_url")
+	if len(authReqs) != 1 {
+		t.Fatalf("%d auth_url requests, want 1", len(authReqs))
+	}
+	body := authReqs[0].Body
+	if body["role"] != "dev" || body["redirect_uri"] != oa.redirectURI() {
+		t.Errorf("auth_url request = %v", body)
+	}
+	nonce, _ := body["client_nonce"].(string)
+	if len(nonce) == 0 {
+		t.Error("auth_url request has no client_nonce")
+	}
+
+	callbacks := fv.received("auth/oidc/oidc/callback")
+	if len(callbacks) != 1 {
+		t.Fatalf("%d callback requests, want 1", len(callbacks))
+	}
+	if callbacks[0].Method != http.MethodGet {
+		t.Errorf("callback method = %s", callbacks[0].Method)
+	}
+}
+
+func TestOIDCCallbackExchange(t *testing.T) {
+	fv, oa := oidcFixture(t, fakeIdP(t))
+	vi := fv.instance(t)
+	vi.Prompter = &browserPrompter{browse: func(authURL string) {
+		http.Get(authURL)
+	}}
+	if _, err := oa.Login(vi); err != nil {
+		t.Fatal(err)
+	}
+
+	// the code is exchanged with the state Vault handed out and the same client nonce
+	nonce := fv.received("auth/oidc/oidc/auth_url")[0].Body["client_nonce"]
+	query := fv.received("auth/oidc/oidc/callback")[0].Query
+	want := map[string]string{"state": "vault-state", "code": "idp-code", "client_nonce": fmt.Sprint(nonce)}
+	for k, v := range want {
+		if got := query.Get(k); got != v {
+			t.Errorf("callback %s = %q, want %q", k, got, v)
+		}
+	}
+}
+
+func TestOIDCLoginFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		browse   func(oa *oidcAuthenticator, authURL string)
+		exchange func(fv *fakeVault)
+		timeout  time.Duration
+		wantErr  string
+	}{
+		{
+			name: "provider error",
+			browse: func(oa *oidcAuthenticator, authURL string) {
+				http.Get(oa.callbackURL(url.Values{"state": {"vault-state"}, "error": {"access_denied"}, "error_description": {"user said no"}}))
+			},
+			wantErr: "access_denied: user said no",
+		},
+		{
+			name: "no code",
+			browse: func(oa *oidcAuthenticator, authURL string) {
+				http.Get(oa.callbackURL(url.Values{"state": {"vault-state"}}))
+			},
+			wantErr: "no code",
+		},
+		{
+			name:    "timeout",
+			browse:  func(*oidcAuthenticator, string) {},
+			timeout: 100 * time.Millisecond,
+			wantErr: "timed out",
+		},
+		{
+			name: "exchange refused",
+			browse: func(oa *oidcAuthenticator, authURL string) {
+				http.Get(authURL)
+			},
+			exchange: func(fv *fakeVault) {
+				fv.handle("auth/oidc/oidc/callback", func(fakeRequest) (int, interface{}) {
+					return http.StatusBadRequest, map[string]interface{}{"errors": []string{"invalid code"}}
+				})
+			},
+			wantErr: "unable to complete OIDC login",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			fv, oa := oidcFixture(t, fakeIdP(t))
+			if tt.timeout > 0 {
+				oa.timeout = tt.timeout
+			}
+			if tt.exchange != nil {
+				tt.exchange(fv)
+			}
+			vi := fv.instance(t)
+			vi.Prompter = &browserPrompter{browse: func(authURL string) {
+				tt.browse(oa, authURL)
+			}}
+			_, err := oa.Login(vi)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOIDCStateMismatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		stray url.Values
+	}{
+		{"code", url.Values{"state": {"someone-else"}, "code": {"stolen"}}},
+		{"error", url.Values{"state": {"someone-else"}, "error": {"access_denied"}}},
+		{"error without state", url.Values{"error": {"access_denied"}}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			fv, oa := oidcFixture(t, fakeIdP(t))
+			vi := fv.instance(t)
+			mismatch := make(chan int, 1)
+			vi.Prompter = &browserPrompter{browse: func(authURL string) {
+				// a stray callback is refused without ending the login
+				resp, err := http.Get(oa.callbackURL(tt.stray))
+				if err != nil {
+					mismatch <- 0
+					return
+				}
+				resp.Body.Close()
+				mismatch <- resp.StatusCode
+				http.Get(authURL)
+			}}
+
+			login, err := oa.Login(vi)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status := <-mismatch; status != http.StatusBadRequest {
+				t.Errorf("mismatched state answered with %d", status)
+			}
+			if got := clientToken(t, login); got != "s.oidc" {
+				t.Errorf("client token = %q", got)
+			}
+		})
+	}
+}
+
+func TestOIDCAuthURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		authURL string
+		wantErr string
+	}{
+		{"no url", "", "no OIDC auth url returned"},
+		{"no state", "https://idp/authorize?nonce=n", "no state"},
+		{"no nonce", "https://idp/authorize?state=s", "no nonce"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fv := newFakeVault(t)
+			fv.reply("auth/oidc/oidc/auth_url", map[string]interface{}{"data": map[string]interface{}{"auth_url": tt.authURL}})
+			oa := &oidcAuthenticator{config: config.OIDCAuth{Role: "dev", CallbackHost: "127.0.0.1", CallbackPort: 8250}}
+			_, _, err := oa.authURL(fv.instance(t), "nonce")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
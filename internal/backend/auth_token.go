@@ -0,0 +1,45 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	"github.com/hashicorp/vault/api"
+	"gopkg.in/yaml.v3"
+)
+
+type tokenAuthenticator struct {
+	config config.TokenAuth
+}
+
+func init() {
+	RegisterAuthenticator("token", newTokenAuthenticator)
+}
+
+// token accepts either `token: <token>` or a block with a token key
+func newTokenAuthenticator(block *yaml.Node) (Authenticator, error) {
+	ta := &tokenAuthenticator{}
+	if block != nil && block.Kind == yaml.ScalarNode {
+		ta.config.Token = block.Value
+	} else if err := decodeBlock(block, &ta.config); err != nil {
+		return nil, err
+	}
+	if len(ta.config.Token) == 0 {
+		return nil, fmt.Errorf("token is empty")
+	}
+	return ta, nil
+}
+
+func (ta *tokenAuthenticator) MountPath() string {
+	return "token"
+}
+
+// it's easy, nothing to call
+func (ta *tokenAuthenticator) Login(vi VaultInstance) (*api.Secret, error) {
+	return &api.Secret{Auth: &api.SecretAuth{ClientToken: ta.config.Token}}, nil
+}
+
+// the configured token is not ours to hand to the token helper
+func (ta *tokenAuthenticator) reused() bool {
+	return true
+}
@@ -0,0 +1,89 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/hashicorp/vault/api"
+	"gopkg.in/yaml.v3"
+)
+
+// tokenHelperAuthenticator uses the token the vault CLI already has, from VAULT_TOKEN
+// or the token helper. With a fallback, a missing or invalid token means logging in with it instead.
+type tokenHelperAuthenticator struct {
+	fallback Authenticator
+	// set once the fallback logged in, the token is then a new one
+	loggedIn bool
+}
+
+func init() {
+	RegisterAuthenticator("tokenHelper", newTokenHelperAuthenticator)
+}
+
+func newTokenHelperAuthenticator(block *yaml.Node) (Authenticator, error) {
+	enabled := true
+	if err := decodeBlock(block, &enabled); err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, fmt.Errorf("tokenHelper is false, remove it and configure another auth method")
+	}
+	return &tokenHelperAuthenticator{}, nil
+}
+
+func (th *tokenHelperAuthenticator) MountPath() string {
+	return "token"
+}
+
+func (th *tokenHelperAuthenticator) Login(vi VaultInstance) (*api.Secret, error) {
+	token, source, err := th.token(vi)
+	if err != nil && th.fallback == nil {
+		return nil, err
+	}
+
+	if len(token) > 0 && th.fallback != nil {
+		// only trust it if it works here, it may belong to another cluster
+		if err := vi.checkToken(token); err != nil {
+			log.Printf("%s: token from %s is not valid here: %v", vi.DisplayName, source, err)
+			token = ""
+		}
+	}
+
+	if len(token) == 0 {
+		if th.fallback != nil {
+			th.loggedIn = true
+			return th.fallback.Login(vi)
+		}
+		return nil, fmt.Errorf("no token in VAULT_TOKEN or the token helper, log in with the vault CLI first")
+	}
+	return &api.Secret{Auth: &api.SecretAuth{ClientToken: token}}, nil
+}
+
+// token prefers VAULT_TOKEN over the helper, as the CLI does
+func (th *tokenHelperAuthenticator) token(vi VaultInstance) (string, string, error) {
+	if token := os.Getenv("VAULT_TOKEN"); len(token) > 0 {
+		return token, "VAULT_TOKEN", nil
+	}
+	helper, err := NewTokenHelper(vi)
+	if err != nil {
+		return "", "", err
+	}
+	token, err := helper.Get()
+	return token, "token helper", err
+}
+
+// checkToken looks the token up on a copy of the client
+func (vi VaultInstance) checkToken(token string) error {
+	client, err := vi.Client.CloneWithHeaders()
+	if err != nil {
+		return err
+	}
+	client.SetToken(token)
+	_, err = client.Auth().Token().LookupSelf()
+	return err
+}
+
+func (th *tokenHelperAuthenticator) reused() bool {
+	return !th.loggedIn
+}
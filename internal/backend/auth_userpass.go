@@ -0,0 +1,57 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	"github.com/fennysoftware/vaultviewer/internal/prompt"
+	"github.com/hashicorp/vault/api"
+	"gopkg.in/yaml.v3"
+)
+
+type userpassAuthenticator struct {
+	config config.UserpassAuth
+}
+
+func init() {
+	RegisterAuthenticator("userpass", newUserpassAuthenticator)
+}
+
+func newUserpassAuthenticator(block *yaml.Node) (Authenticator, error) {
+	ua := &userpassAuthenticator{}
+	if err := decodeBlock(block, &ua.config); err != nil {
+		return nil, err
+	}
+	return ua, nil
+}
+
+func (ua *userpassAuthenticator) MountPath() string {
+	return mountPathOr(ua.config.MountPath, "userpass")
+}
+
+func (ua *userpassAuthenticator) Login(vi VaultInstance) (*api.Secret, error) {
+	username := ua.config.Username
+	pwd := ua.config.Password
+	if len(username) == 0 || len(pwd) == 0 {
+		var err error
+		username, pwd, err = prompt.Credentials(vi.prompter(), vi.DisplayName+" userpass login", username)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// to pass the password
+	options := map[string]interface{}{
+		"password": pwd,
+	}
+
+	// the login path
+	path := fmt.Sprintf("auth/%s/login/%s", ua.MountPath(), username)
+
+	// PUT call to get a token
+	login, err := vi.Client.Logical().Write(path, options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to log in with userpass auth: %w", err)
+	}
+	return login, nil
+}
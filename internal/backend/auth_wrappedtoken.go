@@ -0,0 +1,73 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	"github.com/fennysoftware/vaultviewer/internal/prompt"
+	"github.com/hashicorp/vault/api"
+	"gopkg.in/yaml.v3"
+)
+
+// wrappedTokenAuthenticator unwraps a single use, response-wrapped token
+type wrappedTokenAuthenticator struct {
+	config config.WrappedTokenAuth
+}
+
+func init() {
+	RegisterAuthenticator("wrappedToken", newWrappedTokenAuthenticator)
+}
+
+func newWrappedTokenAuthenticator(block *yaml.Node) (Authenticator, error) {
+	wa := &wrappedTokenAuthenticator{}
+	if err := decodeBlock(block, &wa.config); err != nil {
+		return nil, err
+	}
+	if len(wa.config.ExpectedCreationPath) == 0 {
+		return nil, fmt.Errorf("expectedCreationPath is required so a token wrapped elsewhere is never accepted")
+	}
+	return wa, nil
+}
+
+func (wa *wrappedTokenAuthenticator) MountPath() string {
+	return "token"
+}
+
+func (wa *wrappedTokenAuthenticator) Login(vi VaultInstance) (*api.Secret, error) {
+	wrappingToken, err := readSecret(wa.config.Token, wa.config.TokenFile, "")
+	if err != nil {
+		return nil, fmt.Errorf("error reading wrapped token: %w", err)
+	}
+	if len(wrappingToken) == 0 {
+		wrappingToken, err = prompt.Secret(vi.prompter(), vi.DisplayName+" wrapped token", "Wrapping token")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	wi, err := vi.lookupWrapping(wrappingToken)
+	if err != nil {
+		return nil, err
+	}
+	prompt.Notify(vi.prompter(), vi.DisplayName+" wrapped token", fmt.Sprintf("Created by %s at %s, TTL %s",
+		wi.CreationPath, wi.CreationTime.Format(time.RFC3339), wi.CreationTTL))
+
+	if strings.Trim(wi.CreationPath, "/") != strings.Trim(wa.config.ExpectedCreationPath, "/") {
+		return nil, fmt.Errorf("refusing wrapped token created by %q, expected %q", wi.CreationPath, wa.config.ExpectedCreationPath)
+	}
+
+	unwrapped, err := vi.unwrap(wrappingToken)
+	if err != nil {
+		return nil, err
+	}
+	if unwrapped.Auth != nil && len(unwrapped.Auth.ClientToken) > 0 {
+		return unwrapped, nil
+	}
+	// wrapped token create responses carry the token in the data instead
+	if token, ok := unwrapped.Data["token"].(string); ok && len(token) > 0 {
+		return &api.Secret{Auth: &api.SecretAuth{ClientToken: token}}, nil
+	}
+	return nil, fmt.Errorf("unwrapped response has no client token")
+}
@@ -1,8 +1,6 @@
 package backend
 
 import (
-	"log"
-	"sort"
 	"strings"
 	"time"
 
@@ -38,56 +36,6 @@ const (
 	PatchCapabilityInt
 )
 
-func toPathPermissions(pt map[string]interface{}) []PathPermissions {
-
-	pathps := []PathPermissions{}
-
-	for k, v1 := range pt {
-		pp := PathPermissions{}
-		pp.Path = k
-
-		log.Printf("parsing %s: %v", k, v1)
-		pt1 := v1.(map[string]interface{})
-
-		perms := &ACLPermissions{}
-		perms.MinWrappingTTL = 0
-		perms.MaxWrappingTTL = 0
-		perms.AllowedParameters = map[string][]interface{}{}
-		perms.DeniedParameters = map[string][]interface{}{}
-		perms.RequiredParameters = []string{}
-
-		for k, v2 := range pt1 {
-			log.Printf("parsing %s: %v", k, v2)
-			if k == "capabilities" {
-				capabilities := v2.([]interface{})
-				capabilitiesraw := []string{}
-				res := uint32(0)
-				for _, c := range capabilities {
-					capability := c.(string)
-					if capability != "" {
-						log.Printf("\ncap: %s: %d\n", capability, cap2Int[capability])
-						res |= cap2Int[capability]
-						capabilitiesraw = append(capabilitiesraw, capability)
-					}
-				}
-				log.Printf("\nfinal cap: %d\n", res)
-				if res == 0 {
-					perms.CapabilitiesBitmap = DenyCapabilityInt
-				} else {
-					perms.CapabilitiesBitmap = res
-				}
-				perms.Capabilities = capabilitiesraw
-			}
-		}
-		pp.Permissions = perms
-		pathps = append(pathps, pp)
-	}
-
-	sort.Sort(ppSort(pathps))
-
-	return pathps
-}
-
 type PolicyType uint32
 
 const (
@@ -129,6 +77,11 @@ type Policy struct {
 	Raw       string
 	Type      PolicyType
 	Templated bool
+	// set when the policy couldn't be read or parsed
+	Err error
+	// Sentinel policies only, SentinelPaths are the paths an EGP is attached to
+	EnforcementLevel string
+	SentinelPaths    []string
 }
 
 type PathPermissions struct {
@@ -158,10 +111,16 @@ type ACL struct {
 	//prefixRules *radix.Tree
 	PrefixRules []PathPermissions
 	//segmentWildcardPaths map[string]interface{}
+	// SegmentWildcardRules are paths with + segments, from either list; the ones
+	// that were prefix rules keep their trailing *
+	SegmentWildcardRules []PathPermissions
 
 	// root is enabled if the "root" named policy is present.
 	Root bool
 
+	// anything in the resultant ACL that couldn't be understood, the rest is still usable
+	ParseErrors []error
+
 	// Stores policies that are actually RGPs for later fetching
 	//rgpPolicies []*Policy
 }
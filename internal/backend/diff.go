@@ -0,0 +1,149 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Changes reported for a path by DiffACL
+const (
+	PathAdded   = "added"
+	PathRemoved = "removed"
+	PathChanged = "changed"
+)
+
+// PathDiff is one rule whose capabilities differ between the two sides
+type PathDiff struct {
+	Path     string   `json:"path"`
+	RuleType string   `json:"ruleType"`
+	Change   string   `json:"change"`
+	Left     []string `json:"left"`
+	Right    []string `json:"right"`
+	Gained   []string `json:"gained,omitempty"`
+	Lost     []string `json:"lost,omitempty"`
+}
+
+// ACLDiff is what changed in effective access going from the left ACL to the right one
+type ACLDiff struct {
+	Left      string     `json:"left"`
+	Right     string     `json:"right"`
+	LeftRoot  bool       `json:"leftRoot"`
+	RightRoot bool       `json:"rightRoot"`
+	Paths     []PathDiff `json:"paths"`
+}
+
+// rule of an ACL keyed the way it is written in a policy
+type diffRule struct {
+	ruleType string
+	bitmap   uint32
+}
+
+func (acl ACL) diffRules() map[string]diffRule {
+	rules := map[string]diffRule{}
+	add := func(pps []PathPermissions, ruleType string, isPrefix func(string) bool) {
+		for _, pp := range pps {
+			bitmap := uint32(0)
+			if pp.Permissions != nil {
+				bitmap = pp.Permissions.CapabilitiesBitmap
+			}
+			rules[ruleKey(pp.Path, isPrefix(pp.Path))] = diffRule{ruleType: ruleType, bitmap: bitmap}
+		}
+	}
+	add(acl.ExactRules, RuleExact, func(string) bool { return false })
+	add(acl.PrefixRules, RulePrefix, func(string) bool { return true })
+	add(acl.SegmentWildcardRules, RuleSegmentWildcard, func(path string) bool { return strings.HasSuffix(path, "*") })
+	return rules
+}
+
+// DiffACL compares two loaded instances, they can be different clusters, namespaces or logins
+func DiffACL(left *VaultInstance, right *VaultInstance) ACLDiff {
+	diff := ACLDiff{
+		Left:      left.DisplayName,
+		Right:     right.DisplayName,
+		LeftRoot:  left.Acl.Root,
+		RightRoot: right.Acl.Root,
+		Paths:     []PathDiff{},
+	}
+	lrules := left.Acl.diffRules()
+	rrules := right.Acl.diffRules()
+
+	paths := []string{}
+	for path := range lrules {
+		paths = append(paths, path)
+	}
+	for path := range rrules {
+		if _, ok := lrules[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		l, lok := lrules[path]
+		r, rok := rrules[path]
+		if lok && rok && l.bitmap == r.bitmap {
+			continue
+		}
+		pd := PathDiff{
+			Path:   path,
+			Left:   CapabilityNames(l.bitmap),
+			Right:  CapabilityNames(r.bitmap),
+			Gained: CapabilityNames(r.bitmap &^ l.bitmap),
+			Lost:   CapabilityNames(l.bitmap &^ r.bitmap),
+		}
+		switch {
+		case !lok:
+			pd.Change = PathAdded
+			pd.RuleType = r.ruleType
+		case !rok:
+			pd.Change = PathRemoved
+			pd.RuleType = l.ruleType
+		default:
+			pd.Change = PathChanged
+			pd.RuleType = r.ruleType
+		}
+		diff.Paths = append(diff.Paths, pd)
+	}
+	return diff
+}
+
+// Text is the diff as a plain report
+func (d ACLDiff) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", d.Left, d.Right)
+	if d.LeftRoot != d.RightRoot {
+		fmt.Fprintf(&b, "root: %v -> %v\n", d.LeftRoot, d.RightRoot)
+	}
+	if len(d.Paths) == 0 {
+		b.WriteString("no differences\n")
+	}
+	for _, pd := range d.Paths {
+		switch pd.Change {
+		case PathAdded:
+			fmt.Fprintf(&b, "+ %s (%s): %s\n", pd.Path, pd.RuleType, strings.Join(pd.Right, ", "))
+		case PathRemoved:
+			fmt.Fprintf(&b, "- %s (%s): %s\n", pd.Path, pd.RuleType, strings.Join(pd.Left, ", "))
+		default:
+			fmt.Fprintf(&b, "~ %s (%s):", pd.Path, pd.RuleType)
+			if len(pd.Gained) > 0 {
+				fmt.Fprintf(&b, " +%s", strings.Join(pd.Gained, " +"))
+			}
+			if len(pd.Lost) > 0 {
+				fmt.Fprintf(&b, " -%s", strings.Join(pd.Lost, " -"))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// JSON is the diff as an indented JSON report
+func (d ACLDiff) JSON() (string, error) {
+	data, err := json.MarshalIndent(&d, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
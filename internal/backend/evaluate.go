@@ -0,0 +1,254 @@
+package backend
+
+import (
+	"sort"
+	"strings"
+)
+
+// capability names in display order with their bits
+var capabilityOrder = []struct {
+	name string
+	bit  uint32
+}{
+	{CreateCapability, CreateCapabilityInt},
+	{ReadCapability, ReadCapabilityInt},
+	{UpdateCapability, UpdateCapabilityInt},
+	{PatchCapability, PatchCapabilityInt},
+	{DeleteCapability, DeleteCapabilityInt},
+	{ListCapability, ListCapabilityInt},
+	{SudoCapability, SudoCapabilityInt},
+	{DenyCapability, DenyCapabilityInt},
+}
+
+// allCapabilitiesInt is everything a root token can do
+const allCapabilitiesInt = CreateCapabilityInt | ReadCapabilityInt | UpdateCapabilityInt | PatchCapabilityInt |
+	DeleteCapabilityInt | ListCapabilityInt | SudoCapabilityInt
+
+// Rule types reported by Evaluate
+const (
+	RuleRoot            = "root"
+	RuleExact           = "exact"
+	RulePrefix          = "prefix"
+	RuleSegmentWildcard = "segment wildcard"
+	RuleNone            = "none"
+)
+
+// CapabilityNames lists the capabilities set in a bitmap
+func CapabilityNames(bitmap uint32) []string {
+	names := []string{}
+	for _, c := range capabilityOrder {
+		if bitmap&c.bit != 0 {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+// Evaluation is the effective access the ACL gives on a path
+type Evaluation struct {
+	Path         string
+	Capabilities []string
+	Bitmap       uint32
+	// the rule that decided it, nil for root or when nothing matched
+	Rule     *PathPermissions
+	RuleType string
+	Denied   bool
+}
+
+// Allows is true when the capability is granted on the path
+func (e Evaluation) Allows(capability string) bool {
+	return !e.Denied && e.Bitmap&cap2Int[capability] != 0
+}
+
+// a rule that matched the path, with what Vault ranks non exact matches by
+type ruleMatch struct {
+	pp        *PathPermissions
+	ruleType  string
+	path      string
+	firstWC   int
+	isPrefix  bool
+	wildcards int
+}
+
+// Evaluate works out the capabilities on path the way Vault does: root allows everything,
+// an exact rule wins outright, otherwise the most specific of the longest prefix rule and
+// the matching segment wildcard rules is used. A deny on the chosen rule removes everything.
+func (acl ACL) Evaluate(path string) Evaluation {
+	path = strings.TrimPrefix(path, "/")
+	ev := Evaluation{Path: path, RuleType: RuleNone, Capabilities: []string{}}
+
+	if acl.Root {
+		ev.RuleType = RuleRoot
+		ev.Bitmap = allCapabilitiesInt
+		ev.Capabilities = CapabilityNames(ev.Bitmap)
+		return ev
+	}
+
+	match := acl.match(path)
+	if match == nil || match.pp.Permissions == nil {
+		return ev
+	}
+	ev.Rule = match.pp
+	ev.RuleType = match.ruleType
+	ev.Bitmap = match.pp.Permissions.CapabilitiesBitmap
+	if ev.Bitmap&DenyCapabilityInt != 0 {
+		ev.Denied = true
+		ev.Bitmap = DenyCapabilityInt
+	}
+	ev.Capabilities = CapabilityNames(ev.Bitmap)
+	return ev
+}
+
+func (acl ACL) match(path string) *ruleMatch {
+	for i := range acl.ExactRules {
+		pp := &acl.ExactRules[i]
+		if pp.Path == path {
+			return &ruleMatch{pp: pp, ruleType: RuleExact, path: pp.Path}
+		}
+	}
+
+	candidates := []*ruleMatch{}
+
+	// longest plain prefix
+	var prefix *ruleMatch
+	for i := range acl.PrefixRules {
+		pp := &acl.PrefixRules[i]
+		rule := strings.TrimSuffix(pp.Path, "*")
+		if !strings.HasPrefix(path, rule) {
+			continue
+		}
+		if prefix == nil || len(rule) > len(prefix.path) {
+			prefix = &ruleMatch{pp: pp, ruleType: RulePrefix, path: rule, firstWC: len(rule), isPrefix: true}
+		}
+	}
+	if prefix != nil {
+		candidates = append(candidates, prefix)
+	}
+
+	for _, rule := range acl.segmentWildcardRules() {
+		if m := matchSegments(path, rule); m != nil {
+			candidates = append(candidates, m)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return moreSpecific(candidates[i], candidates[j])
+	})
+	return candidates[0]
+}
+
+// moreSpecific is Vault's ordering of non exact matches
+func moreSpecific(a *ruleMatch, b *ruleMatch) bool {
+	// the later the first wildcard or glob, the better
+	if a.firstWC != b.firstWC {
+		return a.firstWC > b.firstWC
+	}
+	// no glob beats a glob
+	if a.isPrefix != b.isPrefix {
+		return !a.isPrefix
+	}
+	// fewer + segments
+	if a.wildcards != b.wildcards {
+		return a.wildcards < b.wildcards
+	}
+	// longer path
+	if len(a.path) != len(b.path) {
+		return len(a.path) > len(b.path)
+	}
+	return a.path > b.path
+}
+
+// segmentWildcardRules are the + rules ready for matching, a trailing * makes one a prefix rule
+func (acl ACL) segmentWildcardRules() []*ruleMatch {
+	rules := []*ruleMatch{}
+	for i := range acl.SegmentWildcardRules {
+		pp := &acl.SegmentWildcardRules[i]
+		rules = append(rules, &ruleMatch{
+			pp:       pp,
+			ruleType: RuleSegmentWildcard,
+			path:     strings.TrimSuffix(pp.Path, "*"),
+			isPrefix: strings.HasSuffix(pp.Path, "*"),
+		})
+	}
+	return rules
+}
+
+// SplitSegmentWildcards moves rules with + segments out of the exact and prefix
+// lists into SegmentWildcardRules, as Vault keeps them apart
+func (acl *ACL) SplitSegmentWildcards() {
+	exact := []PathPermissions{}
+	prefix := []PathPermissions{}
+	for _, pp := range acl.ExactRules {
+		if hasSegmentWildcard(pp.Path) {
+			acl.SegmentWildcardRules = append(acl.SegmentWildcardRules, pp)
+		} else {
+			exact = append(exact, pp)
+		}
+	}
+	for _, pp := range acl.PrefixRules {
+		if hasSegmentWildcard(pp.Path) {
+			if !strings.HasSuffix(pp.Path, "*") {
+				pp.Path += "*"
+			}
+			acl.SegmentWildcardRules = append(acl.SegmentWildcardRules, pp)
+		} else {
+			prefix = append(prefix, pp)
+		}
+	}
+	acl.ExactRules = exact
+	acl.PrefixRules = prefix
+	sort.Sort(ppSort(acl.SegmentWildcardRules))
+}
+
+// matchSegments checks path against a rule with + segments, the last segment
+// of a prefix rule only has to start the matching path segment
+func matchSegments(path string, rule *ruleMatch) *ruleMatch {
+	pathParts := strings.Split(path, "/")
+	ruleParts := strings.Split(rule.path, "/")
+	if rule.isPrefix {
+		if len(pathParts) < len(ruleParts) {
+			return nil
+		}
+	} else if len(pathParts) != len(ruleParts) {
+		return nil
+	}
+
+	wildcards := 0
+	for i, part := range ruleParts {
+		if part == "+" {
+			wildcards++
+			continue
+		}
+		if rule.isPrefix && i == len(ruleParts)-1 {
+			if !strings.HasPrefix(pathParts[i], part) {
+				return nil
+			}
+			continue
+		}
+		if part != pathParts[i] {
+			return nil
+		}
+	}
+
+	m := *rule
+	m.wildcards = wildcards
+	m.firstWC = strings.Index(rule.path, "+")
+	return &m
+}
+
+func hasSegmentWildcard(path string) bool {
+	for _, part := range strings.Split(strings.TrimSuffix(path, "*"), "/") {
+		if part == "+" {
+			return true
+		}
+	}
+	return false
+}
+
+// CapabilitiesSelf asks Vault what the token can do on path, to check Evaluate against
+func (vi VaultInstance) CapabilitiesSelf(path string) ([]string, error) {
+	return vi.Client.Sys().CapabilitiesSelf(strings.TrimPrefix(path, "/"))
+}
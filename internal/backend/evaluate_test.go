@@ -0,0 +1,223 @@
+package backend
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// evaluateFixtures are resultant ACLs as sys/internal/ui/resultant-acl returns them,
+// rules for the same path already merged by Vault
+var evaluateFixtures = map[string]string{
+	"allow": `{
+  "exact_paths": {
+    "secret/foo": {"capabilities": ["create", "read", "update"]},
+    "secret/+/teams": {"capabilities": ["update"]},
+    "apps/+/config": {"capabilities": ["read"]},
+    "multi/+/x/+": {"capabilities": ["read"]},
+    "multi/+/+/y": {"capabilities": ["update"]}
+  },
+  "glob_paths": {
+    "secret/": {"capabilities": ["read"]},
+    "secret/foo/": {"capabilities": ["delete"]},
+    "secret/foo/bar": {"capabilities": ["list"]},
+    "shared/": {"capabilities": ["read", "list"]},
+    "secret/+/": {"capabilities": ["patch"]},
+    "kv/+/data/": {"capabilities": ["read"]},
+    "kv/team/data/": {"capabilities": ["create"]},
+    "apps/+/config": {"capabilities": ["list"]},
+    "long/+/": {"capabilities": ["read"]},
+    "long/+/abc": {"capabilities": ["update"]}
+  }
+}`,
+	"deny": `{
+  "exact_paths": {
+    "secret/foo": {"capabilities": ["create", "read", "update"]},
+    "secret/foo/locked": {"capabilities": ["deny"]},
+    "apps/+/config": {"capabilities": ["deny"]}
+  },
+  "glob_paths": {
+    "secret/": {"capabilities": ["read"]},
+    "secret/foo/": {"capabilities": ["delete"]},
+    "secret/denied/": {"capabilities": ["deny"]},
+    "shared/": {"capabilities": ["deny"]}
+  }
+}`,
+}
+
+func fixtureACL(t *testing.T, name string) ACL {
+	t.Helper()
+	data := map[string]map[string]interface{}{}
+	if err := json.Unmarshal([]byte(evaluateFixtures[name]), &data); err != nil {
+		t.Fatal(err)
+	}
+	acl := ACL{}
+	var errs []error
+	acl.ExactRules, errs = toPathPermissions(data["exact_paths"])
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	acl.PrefixRules, errs = toPathPermissions(data["glob_paths"])
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	acl.SplitSegmentWildcards()
+	return acl
+}
+
+func TestEvaluate(t *testing.T) {
+	acl := fixtureACL(t, "allow")
+
+	tests := []struct {
+		name     string
+		path     string
+		wantRule string
+		wantType string
+		wantCaps []string
+	}{
+		// an exact rule wins over every glob that also matches
+		{"exact beats prefix", "secret/foo", "secret/foo", RuleExact, []string{"create", "read", "update"}},
+		{"leading slash", "/secret/foo", "secret/foo", RuleExact, []string{"create", "read", "update"}},
+		{"prefix", "secret/other", "secret/", RulePrefix, []string{"read"}},
+
+		// the longest matching prefix is used, capabilities of shorter ones are not added
+		{"longest prefix", "secret/foo/barbaz", "secret/foo/bar", RulePrefix, []string{"list"}},
+		{"shorter prefix", "secret/foo/baz", "secret/foo/", RulePrefix, []string{"delete"}},
+
+		// + segments against * globs: the later the first wildcard the better
+		{"glob after the +", "kv/team/data/app", "kv/team/data/", RulePrefix, []string{"create"}},
+		{"+ for other segments", "kv/other/data/app", "kv/+/data/*", RuleSegmentWildcard, []string{"read"}},
+		{"+ beats the shorter glob", "secret/a/teams", "secret/+/teams", RuleSegmentWildcard, []string{"update"}},
+		{"same first wildcard, both globs, fewer + wins", "secret/a/b", "secret/", RulePrefix, []string{"read"}},
+		{"+ needs the segment", "secret/a", "secret/", RulePrefix, []string{"read"}},
+
+		// same first wildcard position: no glob beats a glob
+		{"isPrefix tie", "apps/web/config", "apps/+/config", RuleSegmentWildcard, []string{"read"}},
+		{"isPrefix tie, only the glob matches", "apps/web/configs", "apps/+/config*", RuleSegmentWildcard, []string{"list"}},
+		// then fewer + segments, then the longer path
+		{"fewer + segments", "multi/a/x/y", "multi/+/x/+", RuleSegmentWildcard, []string{"read"}},
+		{"longer segment wildcard path", "long/a/abcdef", "long/+/abc*", RuleSegmentWildcard, []string{"update"}},
+		{"shorter segment wildcard path", "long/a/xyz", "long/+/*", RuleSegmentWildcard, []string{"read"}},
+
+		{"unmatched", "sys/mounts", "", RuleNone, []string{}},
+		{"unmatched + rule", "apps/web/other", "", RuleNone, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := acl.Evaluate(tt.path)
+			if ev.RuleType != tt.wantType {
+				t.Errorf("rule type = %s, want %s", ev.RuleType, tt.wantType)
+			}
+			rule := ""
+			if ev.Rule != nil {
+				rule = ev.Rule.Path
+			}
+			if rule != tt.wantRule {
+				t.Errorf("rule = %q, want %q", rule, tt.wantRule)
+			}
+			if !reflect.DeepEqual(ev.Capabilities, tt.wantCaps) {
+				t.Errorf("capabilities = %v, want %v", ev.Capabilities, tt.wantCaps)
+			}
+			if ev.Denied {
+				t.Error("denied without a deny rule")
+			}
+		})
+	}
+}
+
+func TestEvaluateDeny(t *testing.T) {
+	acl := fixtureACL(t, "deny")
+
+	tests := []struct {
+		name       string
+		path       string
+		wantDenied bool
+		wantCaps   []string
+	}{
+		{"deny prefix over a shorter allow", "secret/denied/x", true, []string{"deny"}},
+		{"exact deny under an allowed prefix", "secret/foo/locked", true, []string{"deny"}},
+		{"deny prefix", "shared/doc", true, []string{"deny"}},
+		{"deny on a + rule", "apps/web/config", true, []string{"deny"}},
+		{"neighbour still allowed", "secret/foo/other", false, []string{"delete"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := acl.Evaluate(tt.path)
+			if ev.Denied != tt.wantDenied {
+				t.Errorf("denied = %v, want %v", ev.Denied, tt.wantDenied)
+			}
+			if !reflect.DeepEqual(ev.Capabilities, tt.wantCaps) {
+				t.Errorf("capabilities = %v, want %v", ev.Capabilities, tt.wantCaps)
+			}
+			if tt.wantDenied && ev.Allows(ReadCapability) {
+				t.Error("a denied path allows read")
+			}
+		})
+	}
+}
+
+func TestEvaluateRoot(t *testing.T) {
+	acl := ACL{Root: true}
+	ev := acl.Evaluate("anything/at/all")
+	if ev.RuleType != RuleRoot || ev.Rule != nil {
+		t.Errorf("rule = %v %s, want root", ev.Rule, ev.RuleType)
+	}
+	want := []string{"create", "read", "update", "patch", "delete", "list", "sudo"}
+	if !reflect.DeepEqual(ev.Capabilities, want) {
+		t.Errorf("capabilities = %v, want %v", ev.Capabilities, want)
+	}
+}
+
+func TestMoreSpecific(t *testing.T) {
+	tests := []struct {
+		name   string
+		better ruleMatch
+		worse  ruleMatch
+	}{
+		{"later first wildcard", ruleMatch{path: "a/b/+", firstWC: 4}, ruleMatch{path: "a/+/c", firstWC: 2}},
+		{"no glob over glob", ruleMatch{path: "a/+/c", firstWC: 2}, ruleMatch{path: "a/+/c", firstWC: 2, isPrefix: true}},
+		{"fewer + segments", ruleMatch{path: "a/+/c/d", firstWC: 2, wildcards: 1}, ruleMatch{path: "a/+/+/d", firstWC: 2, wildcards: 2}},
+		{"longer path", ruleMatch{path: "a/+/cd", firstWC: 2, wildcards: 1}, ruleMatch{path: "a/+/c", firstWC: 2, wildcards: 1}},
+		{"lexically greater", ruleMatch{path: "a/+/d", firstWC: 2, wildcards: 1}, ruleMatch{path: "a/+/c", firstWC: 2, wildcards: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !moreSpecific(&tt.better, &tt.worse) {
+				t.Error("better rule not ranked first")
+			}
+			if moreSpecific(&tt.worse, &tt.better) {
+				t.Error("worse rule ranked first")
+			}
+		})
+	}
+}
+
+func TestMatchSegments(t *testing.T) {
+	tests := []struct {
+		rule      string
+		path      string
+		match     bool
+		wildcards int
+	}{
+		{"secret/+/teams", "secret/a/teams", true, 1},
+		{"secret/+/teams", "secret/a/teams/x", false, 0},
+		{"secret/+/teams", "secret/a/other", false, 0},
+		{"secret/+/+", "secret/a/b", true, 2},
+		{"secret/+/te*", "secret/a/teams/x", true, 1},
+		{"secret/+/te*", "secret/a/other", false, 0},
+		{"secret/+/*", "secret/a", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule+" "+tt.path, func(t *testing.T) {
+			acl := ACL{SegmentWildcardRules: []PathPermissions{{Path: tt.rule}}}
+			m := matchSegments(tt.path, acl.segmentWildcardRules()[0])
+			if (m != nil) != tt.match {
+				t.Fatalf("match = %v, want %v", m != nil, tt.match)
+			}
+			if m != nil && m.wildcards != tt.wildcards {
+				t.Errorf("wildcards = %d, want %d", m.wildcards, tt.wildcards)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package backend
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	vault "github.com/hashicorp/vault/api"
+)
+
+// fakeRequest is a request the fake Vault received
+type fakeRequest struct {
+	Method string
+	Path   string
+	Token  string
+	Query  url.Values
+	Body   map[string]interface{}
+}
+
+// fakeVault answers API paths with canned responses and records every request
+type fakeVault struct {
+	*httptest.Server
+	mu       sync.Mutex
+	handlers map[string]func(r fakeRequest) (int, interface{})
+	requests []fakeRequest
+}
+
+func newFakeVault(t *testing.T) *fakeVault {
+	t.Helper()
+	fv := &fakeVault{handlers: map[string]func(r fakeRequest) (int, interface{}){}}
+	fv.Server = httptest.NewServer(http.HandlerFunc(fv.serve))
+	t.Cleanup(fv.Close)
+	return fv
+}
+
+// handle registers the response for a path, without the /v1/ prefix
+func (fv *fakeVault) handle(path string, h func(r fakeRequest) (int, interface{})) {
+	fv.mu.Lock()
+	defer fv.mu.Unlock()
+	fv.handlers[path] = h
+}
+
+// reply always answers the path with the same body
+func (fv *fakeVault) reply(path string, body interface{}) {
+	fv.handle(path, func(fakeRequest) (int, interface{}) {
+		return http.StatusOK, body
+	})
+}
+
+func (fv *fakeVault) serve(w http.ResponseWriter, r *http.Request) {
+	req := fakeRequest{
+		Method: r.Method,
+		Path:   strings.TrimPrefix(r.URL.Path, "/v1/"),
+		Token:  r.Header.Get("X-Vault-Token"),
+		Query:  r.URL.Query(),
+	}
+	if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+		json.Unmarshal(data, &req.Body)
+	}
+	if r.URL.Query().Get("list") == "true" {
+		req.Method = "LIST"
+	}
+
+	fv.mu.Lock()
+	fv.requests = append(fv.requests, req)
+	h, ok := fv.handlers[req.Path]
+	fv.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"errors":[]}`)
+		return
+	}
+	status, body := h(req)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// received returns the requests made to a path
+func (fv *fakeVault) received(path string) []fakeRequest {
+	fv.mu.Lock()
+	defer fv.mu.Unlock()
+	found := []fakeRequest{}
+	for _, r := range fv.requests {
+		if r.Path == path {
+			found = append(found, r)
+		}
+	}
+	return found
+}
+
+// instance is an unauthenticated instance pointing at the fake
+func (fv *fakeVault) instance(t *testing.T) VaultInstance {
+	t.Helper()
+	vc := &config.VaultConfig{Name: "fake", Address: fv.URL}
+	vi, err := ConnectVaultInstance(vc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vi.Client.ClearToken()
+	return vi
+}
+
+// loginResponse is what an auth method's login endpoint returns
+func loginResponse(token string) map[string]interface{} {
+	return map[string]interface{}{
+		"auth": map[string]interface{}{
+			"client_token": token,
+			"policies":     []string{"default"},
+		},
+	}
+}
+
+// clientToken is the token of a login secret
+func clientToken(t *testing.T, s *vault.Secret) string {
+	t.Helper()
+	if s == nil || s.Auth == nil {
+		t.Fatalf("login returned no auth: %#v", s)
+	}
+	return s.Auth.ClientToken
+}
@@ -0,0 +1,216 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// ErrNoEntity is set for tokens that aren't tied to an identity entity
+var ErrNoEntity = errors.New("the token has no identity entity")
+
+// templatePlaceholder finds {{identity...}} style placeholders in a policy path
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+
+// IdentityAlias is an alias of the entity on one auth mount
+type IdentityAlias struct {
+	ID             string
+	Name           string
+	MountAccessor  string
+	Metadata       map[string]string
+	CustomMetadata map[string]string
+}
+
+// IdentityGroup is a group the entity belongs to, directly or inherited
+type IdentityGroup struct {
+	ID       string
+	Name     string
+	Metadata map[string]string
+}
+
+// Identity is the entity behind the token, what policy templates are resolved against
+type Identity struct {
+	EntityID   string
+	EntityName string
+	Metadata   map[string]string
+	Aliases    []IdentityAlias
+	Groups     []IdentityGroup
+}
+
+// IsTemplated is true when a path contains template placeholders
+func IsTemplated(path string) bool {
+	return templatePlaceholder.MatchString(path)
+}
+
+// GetIdentity reads the token's entity with its aliases and groups
+func (vi VaultInstance) GetIdentity() (*Identity, error) {
+	if len(vi.Token.EntityID) == 0 {
+		return nil, ErrNoEntity
+	}
+	secret, err := vi.Client.Logical().Read("identity/entity/id/" + vi.Token.EntityID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read entity %s: %w", vi.Token.EntityID, err)
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("entity %s not found", vi.Token.EntityID)
+	}
+
+	id := &Identity{EntityID: vi.Token.EntityID}
+	id.EntityName, _ = secret.Data["name"].(string)
+	id.Metadata = toStringMap(secret.Data["metadata"])
+	if aliases, ok := secret.Data["aliases"].([]interface{}); ok {
+		for _, a := range aliases {
+			am, ok := a.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			alias := IdentityAlias{}
+			alias.ID, _ = am["id"].(string)
+			alias.Name, _ = am["name"].(string)
+			alias.MountAccessor, _ = am["mount_accessor"].(string)
+			alias.Metadata = toStringMap(am["metadata"])
+			alias.CustomMetadata = toStringMap(am["custom_metadata"])
+			id.Aliases = append(id.Aliases, alias)
+		}
+	}
+
+	groupIDs, _ := toStrings(secret.Data["group_ids"])
+	for _, gid := range groupIDs {
+		group := IdentityGroup{ID: gid}
+		gs, err := vi.Client.Logical().Read("identity/group/id/" + gid)
+		if err != nil || gs == nil {
+			// the id still resolves, names and metadata won't
+			log.Printf("%s: unable to read group %s: %v", vi.DisplayName, gid, err)
+		} else {
+			group.Name, _ = gs.Data["name"].(string)
+			group.Metadata = toStringMap(gs.Data["metadata"])
+		}
+		id.Groups = append(id.Groups, group)
+	}
+	return id, nil
+}
+
+func toStringMap(v interface{}) map[string]string {
+	out := map[string]string{}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return out
+	}
+	for k, mv := range m {
+		if s, ok := mv.(string); ok {
+			out[k] = s
+		}
+	}
+	return out
+}
+
+// Resolve fills in the placeholders of a templated path. Placeholders that can't be resolved
+// are returned, Vault leaves the whole path out of the token's ACL in that case.
+func (id *Identity) Resolve(path string) (string, []string) {
+	unresolved := []string{}
+	resolved := templatePlaceholder.ReplaceAllStringFunc(path, func(placeholder string) string {
+		name := templatePlaceholder.FindStringSubmatch(placeholder)[1]
+		value, ok := id.lookup(name)
+		if !ok {
+			unresolved = append(unresolved, name)
+			return placeholder
+		}
+		return value
+	})
+	return resolved, unresolved
+}
+
+// lookup resolves one placeholder, the names are the ones Vault's templating supports
+func (id *Identity) lookup(name string) (string, bool) {
+	if id == nil {
+		return "", false
+	}
+	parts := strings.Split(name, ".")
+	if len(parts) < 3 || parts[0] != "identity" {
+		return "", false
+	}
+	get := func(m map[string]string, key string) (string, bool) {
+		v, ok := m[key]
+		return v, ok
+	}
+
+	switch parts[1] {
+	case "entity":
+		switch {
+		case name == "identity.entity.id":
+			return id.EntityID, true
+		case name == "identity.entity.name":
+			return id.EntityName, len(id.EntityName) > 0
+		case parts[2] == "metadata" && len(parts) > 3:
+			return get(id.Metadata, strings.Join(parts[3:], "."))
+		case parts[2] == "aliases" && len(parts) > 4:
+			for _, a := range id.Aliases {
+				if a.MountAccessor != parts[3] {
+					continue
+				}
+				switch {
+				case parts[4] == "id" && len(parts) == 5:
+					return a.ID, true
+				case parts[4] == "name" && len(parts) == 5:
+					return a.Name, true
+				case parts[4] == "metadata" && len(parts) > 5:
+					return get(a.Metadata, strings.Join(parts[5:], "."))
+				case parts[4] == "custom_metadata" && len(parts) > 5:
+					return get(a.CustomMetadata, strings.Join(parts[5:], "."))
+				}
+			}
+		}
+	case "groups":
+		if len(parts) < 5 {
+			return "", false
+		}
+		for _, g := range id.Groups {
+			switch {
+			case parts[2] == "ids" && g.ID == parts[3]:
+			case parts[2] == "names" && len(g.Name) > 0 && g.Name == parts[3]:
+			default:
+				continue
+			}
+			switch {
+			case parts[4] == "id" && len(parts) == 5:
+				return g.ID, true
+			case parts[4] == "name" && len(parts) == 5:
+				return g.Name, len(g.Name) > 0
+			case parts[4] == "metadata" && len(parts) > 5:
+				return get(g.Metadata, strings.Join(parts[5:], "."))
+			}
+		}
+	}
+	return "", false
+}
+
+// ResolveRule is the rule path as it applies to the token, with the placeholders that couldn't be filled
+func (vi *VaultInstance) ResolveRule(pr *PathRules) (string, []string) {
+	if !IsTemplated(pr.Path) {
+		return pr.RulePath(), nil
+	}
+	return vi.Entity.Resolve(pr.RulePath())
+}
+
+// loadIdentity fetches the entity when any policy is templated, so every templated policy
+// can be shown as it would apply to this token
+func (vi VaultInstance) loadIdentity() VaultInstance {
+	vi.Entity = nil
+	vi.EntityErr = nil
+	templated := false
+	for _, p := range vi.Policies {
+		if p.Templated {
+			templated = true
+		}
+	}
+	if !templated {
+		return vi
+	}
+	vi.Entity, vi.EntityErr = vi.GetIdentity()
+	if vi.EntityErr != nil {
+		log.Printf("%s: templated policies can't be resolved: %v", vi.DisplayName, vi.EntityErr)
+	}
+	return vi
+}
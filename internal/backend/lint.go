@@ -0,0 +1,250 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+)
+
+type Severity int
+
+const (
+	SeverityLow Severity = iota
+	SeverityMedium
+	SeverityHigh
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	}
+	return ""
+}
+
+// ParseSeverity reads a severity name as used on the command line
+func ParseSeverity(name string) (Severity, error) {
+	for _, s := range []Severity{SeverityLow, SeverityMedium, SeverityHigh} {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return SeverityLow, fmt.Errorf("unknown severity %q, use low, medium or high", name)
+}
+
+// LintRule is one of the checks the linter runs
+type LintRule struct {
+	ID          string
+	Severity    Severity
+	Description string
+}
+
+var (
+	LintSudoOnWildcard    = LintRule{"VV001", SeverityHigh, "sudo on a wildcard path"}
+	LintAllPaths          = LintRule{"VV002", SeverityHigh, "capabilities on every path (*)"}
+	LintSysWrite          = LintRule{"VV003", SeverityHigh, "write access to sys/"}
+	LintTokenCreate       = LintRule{"VV004", SeverityMedium, "auth/token/create without parameter restrictions"}
+	LintShadowedDeny      = LintRule{"VV005", SeverityMedium, "deny rule bypassed by a more specific grant"}
+	LintDuplicatePath     = LintRule{"VV006", SeverityLow, "path written more than once"}
+	LintOverlappingPath   = LintRule{"VV007", SeverityLow, "path already covered by a prefix rule of the same policy"}
+	LintLegacyWritePolicy = LintRule{"VV008", SeverityLow, "legacy policy = \"write\""}
+
+	LintRules = []LintRule{LintSudoOnWildcard, LintAllPaths, LintSysWrite, LintTokenCreate, LintShadowedDeny, LintDuplicatePath, LintOverlappingPath, LintLegacyWritePolicy}
+)
+
+const writeCapabilitiesInt = CreateCapabilityInt | UpdateCapabilityInt | DeleteCapabilityInt | PatchCapabilityInt | SudoCapabilityInt
+
+var tokenCreatePaths = []string{"auth/token/create", "auth/token/create-orphan", "auth/token/create/"}
+
+// Finding is a risky pattern found in a policy or the resultant ACL
+type Finding struct {
+	RuleID   string   `json:"rule"`
+	Severity Severity `json:"-"`
+	Level    string   `json:"severity"`
+	Policy   string   `json:"policy"`
+	Path     string   `json:"path"`
+	Message  string   `json:"message"`
+}
+
+func newFinding(rule LintRule, policy string, path string, message string) Finding {
+	return Finding{RuleID: rule.ID, Severity: rule.Severity, Level: rule.Severity.String(), Policy: policy, Path: path, Message: message}
+}
+
+// Covers is true when the rule applies to path
+func (pr *PathRules) Covers(path string) bool {
+	if pr.HasSegmentWildcards {
+		return matchSegments(path, &ruleMatch{path: pr.Path, isPrefix: pr.IsPrefix}) != nil
+	}
+	if pr.IsPrefix {
+		return strings.HasPrefix(path, pr.Path)
+	}
+	return pr.Path == path
+}
+
+// Lint checks the policies and the resultant ACL, suppressed findings are left out.
+// Findings are sorted with the most severe first.
+func Lint(policies []*Policy, acl ACL, lc *config.LintConfig) []Finding {
+	findings := []Finding{}
+	for _, p := range policies {
+		findings = append(findings, lintPolicy(p)...)
+	}
+	findings = append(findings, lintShadowedDenies(acl)...)
+
+	kept := []Finding{}
+	for _, f := range findings {
+		if !suppressed(f, lc) {
+			kept = append(kept, f)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		if kept[i].Severity != kept[j].Severity {
+			return kept[i].Severity > kept[j].Severity
+		}
+		if kept[i].RuleID != kept[j].RuleID {
+			return kept[i].RuleID < kept[j].RuleID
+		}
+		if kept[i].Policy != kept[j].Policy {
+			return kept[i].Policy < kept[j].Policy
+		}
+		return kept[i].Path < kept[j].Path
+	})
+	return kept
+}
+
+func lintPolicy(p *Policy) []Finding {
+	findings := []Finding{}
+	seen := map[string]bool{}
+	for _, pr := range p.Paths {
+		path := pr.RulePath()
+		bitmap := pr.Permissions.CapabilitiesBitmap
+		deny := bitmap&DenyCapabilityInt != 0
+		wildcard := pr.IsPrefix || pr.HasSegmentWildcards
+
+		if seen[path] {
+			findings = append(findings, newFinding(LintDuplicatePath, p.Name, path, "the path is written more than once in the policy"))
+		}
+		seen[path] = true
+
+		if pr.Policy == OldWritePathPolicy {
+			findings = append(findings, newFinding(LintLegacyWritePolicy, p.Name, path, "use capabilities instead of policy = \"write\""))
+		}
+
+		for _, other := range p.Paths {
+			if other != pr && other.IsPrefix && !other.HasSegmentWildcards && len(other.Path) < len(pr.Path) && strings.HasPrefix(pr.Path, other.Path) {
+				findings = append(findings, newFinding(LintOverlappingPath, p.Name, path, "also covered by "+other.RulePath()))
+				break
+			}
+		}
+
+		if deny {
+			continue
+		}
+		if wildcard && bitmap&SudoCapabilityInt != 0 {
+			findings = append(findings, newFinding(LintSudoOnWildcard, p.Name, path, "sudo is granted on every path the wildcard matches"))
+		}
+		if pr.IsPrefix && len(pr.Path) == 0 && bitmap != 0 {
+			findings = append(findings, newFinding(LintAllPaths, p.Name, path, "grants "+strings.Join(CapabilityNames(bitmap), ", ")+" on every path"))
+		}
+		if bitmap&writeCapabilitiesInt != 0 && (strings.HasPrefix(pr.Path, "sys/") || pr.Covers("sys/")) {
+			findings = append(findings, newFinding(LintSysWrite, p.Name, path, "grants "+strings.Join(CapabilityNames(bitmap&writeCapabilitiesInt), ", ")+" under sys/"))
+		}
+		if bitmap&(CreateCapabilityInt|UpdateCapabilityInt) != 0 && unrestricted(pr.Permissions) {
+			for _, tp := range tokenCreatePaths {
+				if pr.Covers(tp) {
+					findings = append(findings, newFinding(LintTokenCreate, p.Name, path, "tokens can be created with any policies, TTL or parameters"))
+					break
+				}
+			}
+		}
+	}
+	return findings
+}
+
+func unrestricted(perms *ACLPermissions) bool {
+	return len(perms.AllowedParameters) == 0 && len(perms.DeniedParameters) == 0 && len(perms.RequiredParameters) == 0
+}
+
+// lintShadowedDenies looks for deny rules of the resultant ACL that lose to a more specific grant
+// inside their scope, so the deny doesn't apply where it seems to
+func lintShadowedDenies(acl ACL) []Finding {
+	findings := []Finding{}
+	denies := []*PathRules{}
+	add := func(pps []PathPermissions, isPrefix func(string) bool) {
+		for _, pp := range pps {
+			if pp.Permissions == nil || pp.Permissions.CapabilitiesBitmap&DenyCapabilityInt == 0 {
+				continue
+			}
+			path := strings.TrimSuffix(pp.Path, "*")
+			denies = append(denies, &PathRules{Path: path, IsPrefix: isPrefix(pp.Path), HasSegmentWildcards: hasSegmentWildcard(path), Permissions: pp.Permissions})
+		}
+	}
+	add(acl.PrefixRules, func(string) bool { return true })
+	add(acl.SegmentWildcardRules, func(path string) bool { return strings.HasSuffix(path, "*") })
+	if len(denies) == 0 {
+		return findings
+	}
+
+	// only plain rules have a literal path to evaluate
+	grants := []string{}
+	for _, pp := range acl.ExactRules {
+		grants = append(grants, pp.Path)
+	}
+	for _, pp := range acl.PrefixRules {
+		grants = append(grants, strings.TrimSuffix(pp.Path, "*"))
+	}
+	for _, deny := range denies {
+		policy := strings.Join(deny.Permissions.DenyingPolicies(), ", ")
+		if len(policy) == 0 {
+			policy = "(resultant ACL)"
+		}
+		for _, path := range grants {
+			if path == deny.Path || !deny.Covers(path) {
+				continue
+			}
+			ev := acl.Evaluate(path)
+			if ev.Denied || ev.Rule == nil {
+				continue
+			}
+			findings = append(findings, newFinding(LintShadowedDeny, policy, deny.RulePath(),
+				fmt.Sprintf("%s is still allowed %s by %s", path, strings.Join(ev.Capabilities, ", "), ev.Rule.Path)))
+		}
+	}
+	return findings
+}
+
+func suppressed(f Finding, lc *config.LintConfig) bool {
+	if lc == nil {
+		return false
+	}
+	for _, s := range lc.Suppress {
+		if len(s.Rule) > 0 && s.Rule != f.RuleID {
+			continue
+		}
+		if len(s.Policy) > 0 && s.Policy != f.Policy {
+			continue
+		}
+		if len(s.Path) > 0 && s.Path != f.Path {
+			if !strings.HasSuffix(s.Path, "*") || !strings.HasPrefix(f.Path, strings.TrimSuffix(s.Path, "*")) {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// FindingsText is a plain report of the findings
+func FindingsText(name string, findings []Finding) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d findings\n", name, len(findings))
+	for _, f := range findings {
+		fmt.Fprintf(&b, "%-6s %s %s %s: %s\n", f.Level, f.RuleID, f.Policy, f.Path, f.Message)
+	}
+	return b.String()
+}
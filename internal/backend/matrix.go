@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strings"
+)
+
+// MatrixCapabilities are the columns of the capability matrix
+var MatrixCapabilities = []string{CreateCapability, ReadCapability, UpdateCapability, PatchCapability, DeleteCapability, ListCapability, SudoCapability, DenyCapability}
+
+// MatrixRow is one rule of the ACL with its capabilities
+type MatrixRow struct {
+	Path     string
+	RuleType string
+	Bitmap   uint32
+}
+
+// Has is true when the rule lists the capability
+func (r MatrixRow) Has(capability string) bool {
+	return r.Bitmap&cap2Int[capability] != 0
+}
+
+// Matrix lists every rule of the ACL, prefix rules keep their * so they stand out from exact ones
+func (acl ACL) Matrix() []MatrixRow {
+	rows := []MatrixRow{}
+	add := func(pps []PathPermissions, ruleType string, isPrefix func(string) bool) {
+		for _, pp := range pps {
+			row := MatrixRow{Path: ruleKey(pp.Path, isPrefix(pp.Path)), RuleType: ruleType}
+			if pp.Permissions != nil {
+				row.Bitmap = pp.Permissions.CapabilitiesBitmap
+			}
+			rows = append(rows, row)
+		}
+	}
+	add(acl.ExactRules, RuleExact, func(string) bool { return false })
+	add(acl.PrefixRules, RulePrefix, func(string) bool { return true })
+	add(acl.SegmentWildcardRules, RuleSegmentWildcard, func(path string) bool { return strings.HasSuffix(path, "*") })
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Path < rows[j].Path
+	})
+	return rows
+}
+
+// FilterMatrix keeps the rows matching every term, a capability name needs the capability and
+// anything else is a path prefix
+func FilterMatrix(rows []MatrixRow, filter string) []MatrixRow {
+	terms := strings.Fields(filter)
+	out := []MatrixRow{}
+	for _, row := range rows {
+		keep := true
+		for _, term := range terms {
+			if _, ok := cap2Int[term]; ok {
+				keep = keep && row.Has(term)
+			} else {
+				keep = keep && strings.HasPrefix(row.Path, term)
+			}
+		}
+		if keep {
+			out = append(out, row)
+		}
+	}
+	return out
+}
+
+// WriteMatrixCSV exports the rows with a yes/empty cell per capability
+func WriteMatrixCSV(w io.Writer, rows []MatrixRow) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"path", "rule type"}, MatrixCapabilities...)); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := []string{row.Path, row.RuleType}
+		for _, c := range MatrixCapabilities {
+			if row.Has(c) {
+				record = append(record, "yes")
+			} else {
+				record = append(record, "")
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
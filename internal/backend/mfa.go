@@ -0,0 +1,103 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fennysoftware/vaultviewer/internal/prompt"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// mfaChoice is the method picked to satisfy one constraint
+type mfaChoice struct {
+	constraint string
+	passcode   *logical.MFAMethodID
+	push       *logical.MFAMethodID
+}
+
+// completeMFA answers the login MFA requirement, prompting for passcodes and
+// waiting on push methods, and returns the secret from sys/mfa/validate
+func (vi VaultInstance) completeMFA(login *api.Secret) (*api.Secret, error) {
+	requirement := login.Auth.MFARequirement
+	if len(requirement.MFAConstraints) == 0 {
+		return nil, fmt.Errorf("login requires MFA but no MFA methods were returned")
+	}
+
+	choices := mfaChoices(requirement)
+
+	fields := []prompt.Field{}
+	for _, c := range choices {
+		if c.passcode == nil {
+			continue
+		}
+		label := fmt.Sprintf("%s %s passcode", c.constraint, c.passcode.Type)
+		if c.push != nil {
+			label += " (empty for push)"
+		}
+		fields = append(fields, prompt.Field{Label: label, Secret: true})
+	}
+	answers := []string{}
+	if len(fields) > 0 {
+		var err error
+		answers, err = vi.prompter().Ask(vi.DisplayName+" MFA", fields)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	payload := map[string]interface{}{}
+	pushes := []string{}
+	for _, c := range choices {
+		if c.passcode != nil {
+			passcode := strings.TrimSpace(answers[0])
+			answers = answers[1:]
+			if len(passcode) > 0 || c.push == nil {
+				payload[c.passcode.ID] = []string{passcode}
+				continue
+			}
+		}
+		payload[c.push.ID] = []string{}
+		pushes = append(pushes, fmt.Sprintf("%s (%s)", c.constraint, c.push.Type))
+	}
+	if len(pushes) > 0 {
+		prompt.Notify(vi.prompter(), vi.DisplayName+" MFA", "Approve the login on your device for: "+strings.Join(pushes, ", "))
+	}
+
+	// validate waits for any push approvals
+	secret, err := vi.Client.Sys().MFAValidate(requirement.MFARequestID, payload)
+	if err != nil {
+		return nil, fmt.Errorf("MFA validation failed: %w", err)
+	}
+	return secret, nil
+}
+
+// mfaChoices picks, for every constraint, a passcode method and a push method where there are any
+func mfaChoices(requirement *logical.MFARequirement) []mfaChoice {
+	names := []string{}
+	for name := range requirement.MFAConstraints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	choices := []mfaChoice{}
+	for _, name := range names {
+		c := mfaChoice{constraint: name}
+		constraint := requirement.MFAConstraints[name]
+		if constraint == nil {
+			continue
+		}
+		for _, m := range constraint.Any {
+			if m.UsesPasscode && c.passcode == nil {
+				c.passcode = m
+			} else if !m.UsesPasscode && c.push == nil {
+				c.push = m
+			}
+		}
+		if c.passcode != nil || c.push != nil {
+			choices = append(choices, c)
+		}
+	}
+	return choices
+}
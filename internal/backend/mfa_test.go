@@ -0,0 +1,198 @@
+package backend
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	"github.com/fennysoftware/vaultviewer/internal/prompt"
+	"gopkg.in/yaml.v3"
+)
+
+// scriptedPrompter answers every Ask with the same values and records what was asked and shown
+type scriptedPrompter struct {
+	answers  []string
+	asked    [][]prompt.Field
+	notified []string
+}
+
+func (sp *scriptedPrompter) Ask(title string, fields []prompt.Field) ([]string, error) {
+	sp.asked = append(sp.asked, fields)
+	return sp.answers, nil
+}
+
+func (sp *scriptedPrompter) Notify(title string, message string) {
+	sp.notified = append(sp.notified, message)
+}
+
+func mfaMethod(id string, methodType string, passcode bool) map[string]interface{} {
+	return map[string]interface{}{"id": id, "type": methodType, "name": methodType, "uses_passcode": passcode}
+}
+
+// mfaLogin is the userpass login response of a login that still needs MFA
+func mfaLogin(constraints map[string][]map[string]interface{}) map[string]interface{} {
+	mc := map[string]interface{}{}
+	for name, methods := range constraints {
+		mc[name] = map[string]interface{}{"any": methods}
+	}
+	return map[string]interface{}{
+		"auth": map[string]interface{}{
+			"client_token": "",
+			"mfa_requirement": map[string]interface{}{
+				"mfa_request_id":  "request-1",
+				"mfa_constraints": mc,
+			},
+		},
+	}
+}
+
+func userpassConfig(t *testing.T, address string) *config.VaultConfig {
+	vc := &config.VaultConfig{Name: "fake", Address: address}
+	if err := yaml.Unmarshal([]byte("auth:\n  userpass:\n    username: alice\n    password: pw\n"), vc); err != nil {
+		t.Fatal(err)
+	}
+	return vc
+}
+
+func TestLoginMFA(t *testing.T) {
+	tests := []struct {
+		name        string
+		constraints map[string][]map[string]interface{}
+		answers     []string
+		wantLabels  []string
+		wantPayload map[string]interface{}
+		wantPush    string
+	}{
+		{
+			name:        "totp",
+			constraints: map[string][]map[string]interface{}{"otp": {mfaMethod("totp-id", "totp", true)}},
+			answers:     []string{" 123456 "},
+			wantLabels:  []string{"otp totp passcode"},
+			wantPayload: map[string]interface{}{"totp-id": []interface{}{"123456"}},
+		},
+		{
+			name:        "push",
+			constraints: map[string][]map[string]interface{}{"phone": {mfaMethod("duo-id", "duo", false)}},
+			wantPayload: map[string]interface{}{"duo-id": []interface{}{}},
+			wantPush:    "phone (duo)",
+		},
+		{
+			name:        "passcode left empty for push",
+			constraints: map[string][]map[string]interface{}{"either": {mfaMethod("okta-id", "okta", false), mfaMethod("totp-id", "totp", true)}},
+			answers:     []string{""},
+			wantLabels:  []string{"either totp passcode (empty for push)"},
+			wantPayload: map[string]interface{}{"okta-id": []interface{}{}},
+			wantPush:    "either (okta)",
+		},
+		{
+			name: "several constraints",
+			constraints: map[string][]map[string]interface{}{
+				"b-push": {mfaMethod("pingid-id", "pingid", false)},
+				"a-otp":  {mfaMethod("totp-id", "totp", true)},
+				"c-otp":  {mfaMethod("duo-id", "duo", true)},
+			},
+			answers:    []string{"111111", "222222"},
+			wantLabels: []string{"a-otp totp passcode", "c-otp duo passcode"},
+			wantPayload: map[string]interface{}{
+				"totp-id":   []interface{}{"111111"},
+				"pingid-id": []interface{}{},
+				"duo-id":    []interface{}{"222222"},
+			},
+			wantPush: "b-push (pingid)",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			fv := newFakeVault(t)
+			fv.reply("auth/userpass/login/alice", mfaLogin(tt.constraints))
+			fv.handle("sys/mfa/validate", func(r fakeRequest) (int, interface{}) {
+				if r.Body["mfa_request_id"] != "request-1" {
+					return http.StatusBadRequest, map[string]interface{}{"errors": []string{"unknown request"}}
+				}
+				return http.StatusOK, loginResponse("s.mfa")
+			})
+
+			vi := fv.instance(t)
+			sp := &scriptedPrompter{answers: tt.answers}
+			vi.Prompter = sp
+			vi, err := vi.Login(userpassConfig(t, fv.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if vi.Client.Token() != "s.mfa" {
+				t.Errorf("client token = %q", vi.Client.Token())
+			}
+
+			labels := []string{}
+			for _, fields := range sp.asked {
+				for _, f := range fields {
+					if !f.Secret {
+						t.Errorf("passcode field %q is not masked", f.Label)
+					}
+					labels = append(labels, f.Label)
+				}
+			}
+			if len(tt.wantLabels) == 0 && len(sp.asked) > 0 {
+				t.Errorf("prompted for %v without passcode methods", labels)
+			} else if len(tt.wantLabels) > 0 && !reflect.DeepEqual(labels, tt.wantLabels) {
+				t.Errorf("prompted for %v, want %v", labels, tt.wantLabels)
+			}
+
+			validations := fv.received("sys/mfa/validate")
+			if len(validations) != 1 {
+				t.Fatalf("%d validate requests, want 1", len(validations))
+			}
+			if got := validations[0].Body["mfa_payload"]; !reflect.DeepEqual(got, tt.wantPayload) {
+				t.Errorf("mfa_payload = %#v, want %#v", got, tt.wantPayload)
+			}
+
+			pushed := strings.Join(sp.notified, "\n")
+			if len(tt.wantPush) > 0 && !strings.Contains(pushed, tt.wantPush) {
+				t.Errorf("push notice %q does not mention %s", pushed, tt.wantPush)
+			} else if len(tt.wantPush) == 0 && len(sp.notified) > 0 {
+				t.Errorf("unexpected push notice %q", pushed)
+			}
+		})
+	}
+}
+
+func TestLoginMFAFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		login    map[string]interface{}
+		validate int
+		wantErr  string
+	}{
+		{
+			name:    "no methods",
+			login:   mfaLogin(map[string][]map[string]interface{}{}),
+			wantErr: "no MFA methods",
+		},
+		{
+			name:     "wrong passcode",
+			login:    mfaLogin(map[string][]map[string]interface{}{"otp": {mfaMethod("totp-id", "totp", true)}}),
+			validate: http.StatusForbidden,
+			wantErr:  "MFA validation failed",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			fv := newFakeVault(t)
+			fv.reply("auth/userpass/login/alice", tt.login)
+			fv.handle("sys/mfa/validate", func(fakeRequest) (int, interface{}) {
+				return tt.validate, map[string]interface{}{"errors": []string{"failed to validate"}}
+			})
+			vi := fv.instance(t)
+			vi.Prompter = &scriptedPrompter{answers: []string{"000000"}}
+			_, err := vi.Login(userpassConfig(t, fv.URL))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+)
+
+// ParsePolicy reads the HCL of an ACL policy into its path rules, the same way Vault does
+func ParsePolicy(name string, rules string) (*Policy, error) {
+	p := &Policy{Name: name, Raw: rules, Type: PolicyTypeACL}
+
+	root, err := hcl.ParseString(rules)
+	if err != nil {
+		return p, fmt.Errorf("policy %s: %w", name, err)
+	}
+	list, ok := root.Node.(*ast.ObjectList)
+	if !ok {
+		return p, fmt.Errorf("policy %s: does not contain a root object", name)
+	}
+	if err := checkHCLKeys(list, rootPolicyKeys); err != nil {
+		return p, fmt.Errorf("policy %s: %w", name, err)
+	}
+
+	for _, item := range list.Filter("path").Items {
+		if len(item.Keys) == 0 {
+			continue
+		}
+		key := item.Keys[0].Token.Value().(string)
+
+		if err := checkHCLKeys(item.Val, pathRuleKeys); err != nil {
+			return p, fmt.Errorf("policy %s path %q: %w", name, key, err)
+		}
+		pr := &PathRules{}
+		if err := hcl.DecodeObject(pr, item.Val); err != nil {
+			return p, fmt.Errorf("policy %s path %q: %w", name, key, err)
+		}
+		pr.Path = key
+		if strings.HasSuffix(pr.Path, "*") {
+			pr.Path = strings.TrimSuffix(pr.Path, "*")
+			pr.IsPrefix = true
+		}
+		pr.HasSegmentWildcards = hasSegmentWildcard(pr.Path)
+		if IsTemplated(pr.Path) {
+			p.Templated = true
+		}
+
+		perms, err := pr.permissions()
+		if err != nil {
+			return p, fmt.Errorf("policy %s path %q: %w", name, key, err)
+		}
+		perms.grantedBy(p)
+		pr.Permissions = perms
+		p.Paths = append(p.Paths, pr)
+	}
+	return p, nil
+}
+
+// the keys Vault accepts in a policy, anything else is refused when the policy is written
+var (
+	rootPolicyKeys = []string{"name", "path"}
+	pathRuleKeys   = []string{
+		"comment",
+		"policy",
+		"capabilities",
+		"allowed_parameters",
+		"denied_parameters",
+		"required_parameters",
+		"min_wrapping_ttl",
+		"max_wrapping_ttl",
+		"mfa_methods",
+		"control_group",
+	}
+)
+
+// checkHCLKeys refuses keys that aren't valid, like Vault's CheckHCLKeys, as DecodeObject silently drops them
+func checkHCLKeys(node ast.Node, valid []string) error {
+	var list *ast.ObjectList
+	switch n := node.(type) {
+	case *ast.ObjectList:
+		list = n
+	case *ast.ObjectType:
+		list = n.List
+	default:
+		return fmt.Errorf("cannot check HCL keys of type %T", n)
+	}
+
+	validMap := map[string]bool{}
+	for _, v := range valid {
+		validMap[v] = true
+	}
+	invalid := []string{}
+	for _, item := range list.Items {
+		if len(item.Keys) == 0 {
+			continue
+		}
+		key := item.Keys[0].Token.Value().(string)
+		if !validMap[key] {
+			invalid = append(invalid, fmt.Sprintf("invalid key %q on line %d", key, item.Keys[0].Token.Pos.Line))
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("%s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
+
+// RulePath is the path as written in the policy, with the * back on prefix rules
+func (pr *PathRules) RulePath() string {
+	if pr.IsPrefix {
+		return pr.Path + "*"
+	}
+	return pr.Path
+}
+
+// PathPermissions lets a policy rule be shown like a rule of the resultant ACL
+func (pr *PathRules) PathPermissions() PathPermissions {
+	return PathPermissions{Path: pr.RulePath(), Permissions: pr.Permissions}
+}
+
+// permissions turns the HCL fields into the ACLPermissions used everywhere else
+func (pr *PathRules) permissions() (*ACLPermissions, error) {
+	perms := &ACLPermissions{}
+	perms.AllowedParameters = map[string][]interface{}{}
+	perms.DeniedParameters = map[string][]interface{}{}
+	perms.RequiredParameters = []string{}
+	perms.Capabilities = []string{}
+
+	// the old policy = "read" style expands to capabilities
+	switch pr.Policy {
+	case "":
+	case OldDenyPathPolicy:
+		pr.Capabilities = []string{DenyCapability}
+	case OldReadPathPolicy:
+		pr.Capabilities = append(pr.Capabilities, ReadCapability, ListCapability)
+	case OldWritePathPolicy:
+		pr.Capabilities = append(pr.Capabilities, CreateCapability, ReadCapability, UpdateCapability, DeleteCapability, ListCapability)
+	case OldSudoPathPolicy:
+		pr.Capabilities = append(pr.Capabilities, CreateCapability, ReadCapability, UpdateCapability, DeleteCapability, ListCapability, SudoCapability)
+	default:
+		return nil, fmt.Errorf("invalid policy %q", pr.Policy)
+	}
+
+	for _, capability := range pr.Capabilities {
+		if capability == DenyCapability {
+			perms.Capabilities = []string{DenyCapability}
+			perms.CapabilitiesBitmap = DenyCapabilityInt
+			break
+		}
+		bit, ok := cap2Int[capability]
+		if !ok {
+			return nil, fmt.Errorf("invalid capability %q", capability)
+		}
+		if perms.CapabilitiesBitmap&bit == 0 {
+			perms.Capabilities = append(perms.Capabilities, capability)
+		}
+		perms.CapabilitiesBitmap |= bit
+	}
+
+	if pr.MinWrappingTTLHCL != nil {
+		ttl, ok := toDuration(pr.MinWrappingTTLHCL)
+		if !ok {
+			return nil, fmt.Errorf("invalid min_wrapping_ttl %v", pr.MinWrappingTTLHCL)
+		}
+		perms.MinWrappingTTL = ttl
+	}
+	if pr.MaxWrappingTTLHCL != nil {
+		ttl, ok := toDuration(pr.MaxWrappingTTLHCL)
+		if !ok {
+			return nil, fmt.Errorf("invalid max_wrapping_ttl %v", pr.MaxWrappingTTLHCL)
+		}
+		perms.MaxWrappingTTL = ttl
+	}
+	if pr.AllowedParametersHCL != nil {
+		perms.AllowedParameters = pr.AllowedParametersHCL
+	}
+	if pr.DeniedParametersHCL != nil {
+		perms.DeniedParameters = pr.DeniedParametersHCL
+	}
+	if pr.RequiredParametersHCL != nil {
+		perms.RequiredParameters = pr.RequiredParametersHCL
+	}
+	perms.MFAMethods = pr.MFAMethodsHCL
+
+	if pr.ControlGroupHCL != nil {
+		cg := &ControlGroup{}
+		if pr.ControlGroupHCL.TTL != nil {
+			ttl, ok := toDuration(pr.ControlGroupHCL.TTL)
+			if !ok {
+				return nil, fmt.Errorf("invalid control_group ttl %v", pr.ControlGroupHCL.TTL)
+			}
+			cg.TTL = ttl
+		}
+		names := []string{}
+		for name := range pr.ControlGroupHCL.Factors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			factor := pr.ControlGroupHCL.Factors[name]
+			factor.Name = name
+			cg.Factors = append(cg.Factors, factor)
+		}
+		perms.ControlGroup = cg
+	}
+	return perms, nil
+}
+
+// GetPolicies lists sys/policies/acl and parses each policy.
+// Policies that can't be read or parsed are kept with Err set, the rest are still usable.
+func (vi VaultInstance) GetPolicies() (VaultInstance, error) {
+	vi.Policies = nil
+	names, err := vi.Client.Sys().ListPolicies()
+	if err != nil {
+		return vi, fmt.Errorf("unable to list policies: %w", err)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rules, err := vi.Client.Sys().GetPolicy(name)
+		if err != nil {
+			log.Printf("%s: unable to read policy %s: %v", vi.DisplayName, name, err)
+			vi.Policies = append(vi.Policies, &Policy{Name: name, Type: PolicyTypeACL, Err: err})
+			continue
+		}
+		p, err := ParsePolicy(name, rules)
+		if err != nil {
+			log.Printf("%s: %v", vi.DisplayName, err)
+			p.Err = err
+		}
+		vi.Policies = append(vi.Policies, p)
+	}
+	return vi, nil
+}
+
+// loadPolicies fetches and attributes the policies, not every token may list them and the ACL
+// is still usable without them
+func (vi VaultInstance) loadPolicies() VaultInstance {
+	update, err := vi.GetPolicies()
+	if err != nil {
+		log.Printf("%s: %v", vi.DisplayName, err)
+	}
+	vi = update
+	vi.PoliciesErr = err
+	vi = vi.loadIdentity()
+	vi.AttributePolicies()
+	vi = vi.GetSentinelPolicies()
+	if vi.SentinelErr != nil && vi.SentinelErr != ErrSentinelUnavailable {
+		log.Printf("%s: %v", vi.DisplayName, vi.SentinelErr)
+	}
+	return vi
+}
+
+// Reload fetches the ACL and policies again, after a policy was changed
+func (vi VaultInstance) Reload() (VaultInstance, error) {
+	update, err := vi.GetACL()
+	if err != nil {
+		return vi, fmt.Errorf("unable to get ACL: %w", err)
+	}
+	return update.loadPolicies(), nil
+}
+
+// InEffect is true when the policy is attached to the current token, directly or through its identity
+func (vi *VaultInstance) InEffect(policy string) bool {
+	for _, p := range vi.Token.EffectivePolicies() {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
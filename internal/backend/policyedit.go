@@ -0,0 +1,51 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrReadOnly is returned for changes to an instance that is configured read-only
+var ErrReadOnly = errors.New("the instance is read-only")
+
+// ReadOnly is true when the config forbids changing policies
+func (vi VaultInstance) ReadOnly() bool {
+	return vi.Config != nil && vi.Config.ReadOnly
+}
+
+// ValidatePolicy checks the name and parses the HCL the way ParsePolicy does before anything is sent
+func ValidatePolicy(name string, rules string) error {
+	if len(strings.TrimSpace(name)) == 0 {
+		return errors.New("the policy needs a name")
+	}
+	if strings.ContainsAny(name, "/ ") {
+		return fmt.Errorf("invalid policy name %q", name)
+	}
+	_, err := ParsePolicy(name, rules)
+	return err
+}
+
+// ServerPolicy reads the current rules of a policy, empty when it doesn't exist
+func (vi VaultInstance) ServerPolicy(name string) (string, error) {
+	return vi.Client.Sys().GetPolicy(name)
+}
+
+// WritePolicy creates or replaces sys/policies/acl/<name>
+func (vi VaultInstance) WritePolicy(name string, rules string) error {
+	if vi.ReadOnly() {
+		return ErrReadOnly
+	}
+	if err := ValidatePolicy(name, rules); err != nil {
+		return err
+	}
+	return vi.Client.Sys().PutPolicy(name, rules)
+}
+
+// DeletePolicy removes sys/policies/acl/<name>
+func (vi VaultInstance) DeletePolicy(name string) error {
+	if vi.ReadOnly() {
+		return ErrReadOnly
+	}
+	return vi.Client.Sys().DeletePolicy(name)
+}
@@ -0,0 +1,71 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidatePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		rules   string
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			policy: "app",
+			rules: `
+name = "app"
+path "secret/app/*" {
+  comment      = "the app's secrets"
+  capabilities = ["read", "list"]
+  allowed_parameters = {
+    "*" = []
+  }
+  min_wrapping_ttl = "1m"
+}
+path "sys/old" {
+  policy = "read"
+}
+`,
+		},
+		{name: "no name", policy: " ", rules: `path "x" { capabilities = ["read"] }`, wantErr: "needs a name"},
+		{name: "slash in name", policy: "a/b", rules: `path "x" { capabilities = ["read"] }`, wantErr: "invalid policy name"},
+		{name: "bad HCL", policy: "app", rules: `path "x" {`, wantErr: "policy app"},
+		{
+			name:    "misspelled capabilities",
+			policy:  "app",
+			rules:   "path \"x\" {\n  capabilites = [\"read\"]\n}\n",
+			wantErr: `invalid key "capabilites" on line 2`,
+		},
+		{
+			name:    "misspelled parameters",
+			policy:  "app",
+			rules:   "path \"x\" {\n  capabilities = [\"update\"]\n  allowed_parameter = {\n    \"a\" = []\n  }\n}\n",
+			wantErr: `invalid key "allowed_parameter"`,
+		},
+		{
+			name:    "misspelled path",
+			policy:  "app",
+			rules:   "paths \"x\" {\n  capabilities = [\"read\"]\n}\n",
+			wantErr: `invalid key "paths" on line 1`,
+		},
+		{name: "unknown capability", policy: "app", rules: `path "x" { capabilities = ["reed"] }`, wantErr: "reed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePolicy(tt.policy, tt.rules)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
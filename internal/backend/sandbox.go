@@ -0,0 +1,229 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// LoadPolicyFiles parses local HCL policy files, each policy is named after its file
+func LoadPolicyFiles(paths []string) ([]*Policy, error) {
+	policies := []*Policy{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		p, err := ParsePolicy(name, string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+// BuildACL merges policies into the same structure GetACL fills from the resultant ACL,
+// combining rules for the same path the way Vault does
+func BuildACL(policies []*Policy) ACL {
+	acl := ACL{}
+	exact := map[string]*ACLPermissions{}
+	prefix := map[string]*ACLPermissions{}
+	for _, p := range policies {
+		if p.Name == "root" {
+			acl.Root = true
+		}
+		for _, pr := range p.Paths {
+			rules := exact
+			if pr.IsPrefix {
+				rules = prefix
+			}
+			existing, ok := rules[pr.Path]
+			if !ok {
+				existing = &ACLPermissions{
+					AllowedParameters:   map[string][]interface{}{},
+					DeniedParameters:    map[string][]interface{}{},
+					RequiredParameters:  []string{},
+					GrantingPoliciesMap: map[uint32][]logical.PolicyInfo{},
+				}
+				rules[pr.Path] = existing
+			}
+			mergePermissions(existing, pr.Permissions)
+		}
+	}
+
+	for path, perms := range exact {
+		acl.ExactRules = append(acl.ExactRules, PathPermissions{Path: path, Permissions: perms})
+	}
+	for path, perms := range prefix {
+		acl.PrefixRules = append(acl.PrefixRules, PathPermissions{Path: path, Permissions: perms})
+	}
+	sort.Sort(ppSort(acl.ExactRules))
+	sort.Sort(ppSort(acl.PrefixRules))
+	acl.SplitSegmentWildcards()
+	return acl
+}
+
+// mergePermissions adds a rule to the rules already seen for its path, a deny wipes everything else
+func mergePermissions(existing *ACLPermissions, perms *ACLPermissions) {
+	for bit, infos := range perms.GrantingPoliciesMap {
+		existing.GrantingPoliciesMap[bit] = append(existing.GrantingPoliciesMap[bit], infos...)
+	}
+	if existing.CapabilitiesBitmap&DenyCapabilityInt != 0 {
+		return
+	}
+	if perms.CapabilitiesBitmap&DenyCapabilityInt != 0 {
+		existing.CapabilitiesBitmap = DenyCapabilityInt
+		existing.Capabilities = []string{DenyCapability}
+		return
+	}
+
+	existing.CapabilitiesBitmap |= perms.CapabilitiesBitmap
+	existing.Capabilities = CapabilityNames(existing.CapabilitiesBitmap)
+	if perms.MinWrappingTTL != 0 && (existing.MinWrappingTTL == 0 || perms.MinWrappingTTL < existing.MinWrappingTTL) {
+		existing.MinWrappingTTL = perms.MinWrappingTTL
+	}
+	if perms.MaxWrappingTTL > existing.MaxWrappingTTL {
+		existing.MaxWrappingTTL = perms.MaxWrappingTTL
+	}
+	mergeParameters(existing.AllowedParameters, perms.AllowedParameters)
+	mergeParameters(existing.DeniedParameters, perms.DeniedParameters)
+	existing.RequiredParameters = mergeStrings(existing.RequiredParameters, perms.RequiredParameters)
+	existing.MFAMethods = mergeStrings(existing.MFAMethods, perms.MFAMethods)
+	if perms.ControlGroup != nil {
+		existing.ControlGroup = perms.ControlGroup
+	}
+}
+
+// mergeParameters keeps an empty list, which allows any value, over specific values
+func mergeParameters(existing map[string][]interface{}, params map[string][]interface{}) {
+	for k, values := range params {
+		current, ok := existing[k]
+		if !ok {
+			// a copy, appending later must not write into the policy's own slice
+			existing[k] = append([]interface{}{}, values...)
+		} else if len(current) > 0 && len(values) == 0 {
+			existing[k] = []interface{}{}
+		} else if len(current) > 0 {
+			existing[k] = append(current, values...)
+		}
+	}
+}
+
+func mergeStrings(existing []string, more []string) []string {
+	seen := map[string]bool{}
+	for _, s := range existing {
+		seen[s] = true
+	}
+	for _, s := range more {
+		if !seen[s] {
+			existing = append(existing, s)
+			seen[s] = true
+		}
+	}
+	return existing
+}
+
+// NewSandbox is an offline instance with its ACL built from the policies, it has no client
+// and nothing is sent to Vault
+func NewSandbox(name string, policies []*Policy) VaultInstance {
+	vi := VaultInstance{}
+	vi.DisplayName = name
+	vi.Config = &config.VaultConfig{Name: name, ReadOnly: true}
+	vi.Policies = policies
+	for _, p := range policies {
+		vi.Token.Policies = append(vi.Token.Policies, p.Name)
+	}
+	vi.Acl = BuildACL(policies)
+	vi.AuthMethod = "sandbox"
+	vi.Sandbox = true
+	vi.State = StateACLLoaded
+	return vi
+}
+
+// Offline is true for sandboxes, which have no connection to query
+func (vi VaultInstance) Offline() bool {
+	return vi.Sandbox
+}
+
+// Simulate builds a sandbox from the token's policies, identity policies included, plus the drafts, a draft with the name of
+// one of the token's policies replaces it
+func (vi VaultInstance) Simulate(drafts []*Policy) (VaultInstance, error) {
+	if vi.PoliciesErr != nil {
+		return vi, fmt.Errorf("the policies of %s are not readable: %w", vi.DisplayName, vi.PoliciesErr)
+	}
+	replaced := map[string]bool{}
+	names := []string{}
+	for _, d := range drafts {
+		replaced[d.Name] = true
+		names = append(names, d.Name)
+	}
+
+	policies := []*Policy{}
+	for _, name := range vi.Token.EffectivePolicies() {
+		if replaced[name] {
+			continue
+		}
+		if name == "root" {
+			policies = append(policies, &Policy{Name: name, Type: PolicyTypeACL})
+			continue
+		}
+		found := false
+		for _, p := range vi.Policies {
+			if p.Name == name && p.Err == nil {
+				policies = append(policies, p)
+				found = true
+			}
+		}
+		if !found {
+			return vi, fmt.Errorf("policy %s of %s could not be read, the simulation would be incomplete", name, vi.DisplayName)
+		}
+	}
+	policies = append(policies, drafts...)
+
+	sandbox := NewSandbox(fmt.Sprintf("%s + %s (sandbox)", vi.DisplayName, strings.Join(names, ", ")), policies)
+	// templated paths apply as they resolve for the token's entity
+	sandbox.Entity = vi.Entity
+	sandbox.EntityErr = vi.EntityErr
+	resolved := []*Policy{}
+	for _, p := range policies {
+		rp, unresolved := sandbox.resolvePolicy(p)
+		resolved = append(resolved, rp)
+		sandbox.Unresolved = append(sandbox.Unresolved, unresolved...)
+	}
+	sandbox.Acl = BuildACL(resolved)
+	return sandbox, nil
+}
+
+// resolvePolicy is a copy of a templated policy with its paths resolved against the entity, paths
+// with placeholders that can't be resolved are left out the way Vault does and returned
+func (vi VaultInstance) resolvePolicy(p *Policy) (*Policy, []string) {
+	if !p.Templated {
+		return p, nil
+	}
+	rp := *p
+	rp.Paths = []*PathRules{}
+	unresolved := []string{}
+	for _, pr := range p.Paths {
+		if !IsTemplated(pr.Path) {
+			rp.Paths = append(rp.Paths, pr)
+			continue
+		}
+		path, missing := vi.Entity.Resolve(pr.Path)
+		if len(missing) > 0 {
+			unresolved = append(unresolved, fmt.Sprintf("%s (%s)", pr.RulePath(), p.Name))
+			continue
+		}
+		rpr := *pr
+		rpr.Path = path
+		rpr.HasSegmentWildcards = hasSegmentWildcard(path)
+		rp.Paths = append(rp.Paths, &rpr)
+	}
+	return &rp, unresolved
+}
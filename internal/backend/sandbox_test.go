@@ -0,0 +1,114 @@
+package backend
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+)
+
+func TestBuildACLKeepsPolicyParameters(t *testing.T) {
+	first, err := ParsePolicy("first", `
+path "secret/app" {
+  capabilities = ["update"]
+  allowed_parameters = {
+    "color" = ["red", "green"]
+  }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ParsePolicy("second", `
+path "secret/app" {
+  capabilities = ["update"]
+  allowed_parameters = {
+    "color" = ["blue"]
+  }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// spare capacity lets an append land in the policy's own backing array
+	own := make([]interface{}, 2, 8)
+	copy(own, first.Paths[0].Permissions.AllowedParameters["color"])
+	first.Paths[0].Permissions.AllowedParameters["color"] = own
+
+	acl := BuildACL([]*Policy{first, second})
+	got := acl.ExactRules[0].Permissions.AllowedParameters["color"]
+	if want := []interface{}{"red", "green", "blue"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("merged color = %v, want %v", got, want)
+	}
+	if want := []interface{}{"red", "green"}; !reflect.DeepEqual(first.Paths[0].Permissions.AllowedParameters["color"], want) {
+		t.Errorf("first policy's color changed to %v", first.Paths[0].Permissions.AllowedParameters["color"])
+	}
+	if own[:3][2] != nil {
+		t.Errorf("merge wrote %v into the first policy's backing array", own[:3][2])
+	}
+}
+
+func TestOffline(t *testing.T) {
+	if !NewSandbox("sandbox", nil).Offline() {
+		t.Error("a sandbox is not offline")
+	}
+	// an instance that never connected has no client either, it is still a live instance
+	if NewVaultInstance(&config.VaultConfig{Name: "live", Address: "https://vault:8200"}).Offline() {
+		t.Error("an unconnected instance is offline")
+	}
+}
+
+func TestSimulateTemplated(t *testing.T) {
+	templated, err := ParsePolicy("per-user", `
+path "secret/{{identity.entity.name}}/*" {
+  capabilities = ["read"]
+}
+path "secret/{{identity.entity.metadata.team}}/*" {
+  capabilities = ["list"]
+}
+path "secret/shared" {
+  capabilities = ["read"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	draft, err := ParsePolicy("draft", `
+path "kv/{{identity.entity.id}}" {
+  capabilities = ["update"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vi := VaultInstance{DisplayName: "live", Policies: []*Policy{templated}}
+	vi.Token.Policies = []string{"per-user"}
+	vi.Entity = &Identity{EntityID: "e-1", EntityName: "alice", Metadata: map[string]string{}}
+
+	sandbox, err := vi.Simulate([]*Policy{draft})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"secret/alice/app", []string{"read"}},
+		{"secret/shared", []string{"read"}},
+		{"kv/e-1", []string{"update"}},
+		{"secret/{{identity.entity.name}}/app", []string{}},
+	}
+	for _, tt := range tests {
+		if got := sandbox.Acl.Evaluate(tt.path).Capabilities; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: capabilities = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	if want := []string{"secret/{{identity.entity.metadata.team}}/* (per-user)"}; !reflect.DeepEqual(sandbox.Unresolved, want) {
+		t.Errorf("unresolved = %v, want %v", sandbox.Unresolved, want)
+	}
+	// the policies themselves are kept as written
+	if sandbox.Policies[0] != templated || templated.Paths[0].Path != "secret/{{identity.entity.name}}/" {
+		t.Error("the token's policy was changed by the simulation")
+	}
+}
@@ -0,0 +1,95 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// ErrSentinelUnavailable is set on instances that aren't Vault Enterprise
+var ErrSentinelUnavailable = errors.New("sentinel policies need Vault Enterprise")
+
+// enterprise checks the version reported by sys/health, Enterprise builds end in +ent
+func (vi VaultInstance) enterprise() (bool, error) {
+	health, err := vi.Client.Sys().Health()
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(health.Version, "+ent"), nil
+}
+
+// GetSentinelPolicies lists sys/policies/rgp and sys/policies/egp.
+// SentinelErr is ErrSentinelUnavailable on OSS servers.
+func (vi VaultInstance) GetSentinelPolicies() VaultInstance {
+	vi.SentinelPolicies = nil
+	vi.SentinelErr = nil
+
+	// the edition doesn't change between reloads
+	if vi.Enterprise == nil {
+		ent, err := vi.enterprise()
+		if err != nil {
+			vi.SentinelErr = fmt.Errorf("unable to read sys/health: %w", err)
+			return vi
+		}
+		vi.Enterprise = &ent
+	}
+	if !*vi.Enterprise {
+		vi.SentinelErr = ErrSentinelUnavailable
+		return vi
+	}
+
+	for _, ptype := range []PolicyType{PolicyTypeRGP, PolicyTypeEGP} {
+		base := "sys/policies/" + ptype.String()
+		list, err := vi.Client.Logical().List(base)
+		if err != nil {
+			vi.SentinelErr = fmt.Errorf("unable to list %s: %w", base, err)
+			return vi
+		}
+		if list == nil {
+			continue
+		}
+		names, ok := toStrings(list.Data["keys"])
+		if !ok {
+			vi.SentinelErr = &ACLParseError{Path: base, Key: "keys", Expected: "list of strings", Got: list.Data["keys"]}
+			return vi
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			p := &Policy{Name: name, Type: ptype}
+			secret, err := vi.Client.Logical().Read(base + "/" + name)
+			if err != nil || secret == nil {
+				log.Printf("%s: unable to read %s/%s: %v", vi.DisplayName, base, name, err)
+				p.Err = fmt.Errorf("unable to read %s/%s: %v", base, name, err)
+			} else {
+				p.Raw, _ = secret.Data["policy"].(string)
+				p.EnforcementLevel, _ = secret.Data["enforcement_level"].(string)
+				p.SentinelPaths, _ = toStrings(secret.Data["paths"])
+			}
+			vi.SentinelPolicies = append(vi.SentinelPolicies, p)
+		}
+	}
+	return vi
+}
+
+// EGPsFor lists the EGPs whose paths overlap a rule path, a trailing * on either side is a prefix
+func (vi *VaultInstance) EGPsFor(path string) []*Policy {
+	egps := []*Policy{}
+	rule := strings.TrimSuffix(path, "*")
+	rulePrefix := strings.HasSuffix(path, "*")
+	for _, p := range vi.SentinelPolicies {
+		if p.Type != PolicyTypeEGP {
+			continue
+		}
+		for _, sp := range p.SentinelPaths {
+			egp := strings.TrimSuffix(sp, "*")
+			egpPrefix := strings.HasSuffix(sp, "*")
+			if rule == egp || (egpPrefix && strings.HasPrefix(rule, egp)) || (rulePrefix && strings.HasPrefix(egp, rule)) {
+				egps = append(egps, p)
+				break
+			}
+		}
+	}
+	return egps
+}
@@ -0,0 +1,71 @@
+package backend
+
+import (
+	"reflect"
+	"testing"
+)
+
+func healthResponse(version string) map[string]interface{} {
+	return map[string]interface{}{"initialized": true, "sealed": false, "standby": false, "version": version}
+}
+
+func TestGetSentinelPoliciesOSS(t *testing.T) {
+	fv := newFakeVault(t)
+	fv.reply("sys/health", healthResponse("1.12.0"))
+
+	vi := fv.instance(t)
+	vi = vi.GetSentinelPolicies()
+	if vi.SentinelErr != ErrSentinelUnavailable {
+		t.Errorf("SentinelErr = %v, want ErrSentinelUnavailable", vi.SentinelErr)
+	}
+	// a reload asks sys/health only once
+	vi = vi.GetSentinelPolicies()
+	if vi.SentinelErr != ErrSentinelUnavailable {
+		t.Errorf("SentinelErr after reload = %v", vi.SentinelErr)
+	}
+	if n := len(fv.received("sys/health")); n != 1 {
+		t.Errorf("%d sys/health requests, want 1", n)
+	}
+}
+
+func TestGetSentinelPoliciesEnterprise(t *testing.T) {
+	fv := newFakeVault(t)
+	fv.reply("sys/health", healthResponse("1.12.0+ent"))
+	fv.reply("sys/policies/rgp", map[string]interface{}{"data": map[string]interface{}{"keys": []string{"business-hours"}}})
+	fv.reply("sys/policies/egp", map[string]interface{}{"data": map[string]interface{}{"keys": []string{"cidr-check"}}})
+	fv.reply("sys/policies/rgp/business-hours", map[string]interface{}{"data": map[string]interface{}{
+		"policy": "main = rule { true }", "enforcement_level": "soft-mandatory",
+	}})
+	fv.reply("sys/policies/egp/cidr-check", map[string]interface{}{"data": map[string]interface{}{
+		"policy": "main = rule { true }", "enforcement_level": "hard-mandatory", "paths": []string{"secret/*", "sys/mounts"},
+	}})
+
+	vi := fv.instance(t).GetSentinelPolicies()
+	if vi.SentinelErr != nil {
+		t.Fatal(vi.SentinelErr)
+	}
+	if len(vi.SentinelPolicies) != 2 {
+		t.Fatalf("%d Sentinel policies, want 2", len(vi.SentinelPolicies))
+	}
+	egp := vi.SentinelPolicies[1]
+	if egp.Type != PolicyTypeEGP || egp.EnforcementLevel != "hard-mandatory" || !reflect.DeepEqual(egp.SentinelPaths, []string{"secret/*", "sys/mounts"}) {
+		t.Errorf("egp = %+v", egp)
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"secret/app", 1},
+		{"secret/*", 1},
+		{"sys/*", 1},
+		{"sys/mounts", 1},
+		{"sys/auth", 0},
+		{"auth/token/create", 0},
+	}
+	for _, tt := range tests {
+		if got := len(vi.EGPsFor(tt.path)); got != tt.want {
+			t.Errorf("EGPsFor(%s) = %d, want %d", tt.path, got, tt.want)
+		}
+	}
+}
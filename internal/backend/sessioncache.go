@@ -0,0 +1,332 @@
+package backend
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/fennysoftware/vaultviewer/internal/config"
+	"github.com/fennysoftware/vaultviewer/internal/prompt"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	defaultSessionCacheMinTTL = 10 * time.Minute
+	sessionCacheIterations    = 600000
+)
+
+// CachedSession is a token kept between runs
+type CachedSession struct {
+	Token      string    `json:"token"`
+	Accessor   string    `json:"accessor"`
+	ExpireTime time.Time `json:"expireTime"`
+}
+
+// sessionCacheFile is what's on disk, the sessions are sealed with AES-GCM
+// under a key derived from the passphrase
+type sessionCacheFile struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// SessionCache keeps tokens encrypted on disk so a restart doesn't mean logging in again.
+// A nil cache does nothing.
+type SessionCache struct {
+	mu       sync.Mutex
+	path     string
+	minTTL   time.Duration
+	envVar   string
+	prompter prompt.Prompter
+
+	unlocked bool
+	disabled bool
+	salt     []byte
+	key      []byte
+	sessions map[string]CachedSession
+}
+
+// NewSessionCache returns nil when no cache is configured
+func NewSessionCache(sc *config.SessionCacheConfig, p prompt.Prompter) (*SessionCache, error) {
+	if sc == nil {
+		return nil, nil
+	}
+	path, err := sessionCachePath(sc)
+	if err != nil {
+		return nil, err
+	}
+	cache := &SessionCache{
+		path:     path,
+		minTTL:   defaultSessionCacheMinTTL,
+		envVar:   sc.PassphraseEnv,
+		prompter: p,
+	}
+	if len(sc.MinTTL) > 0 {
+		cache.minTTL, err = time.ParseDuration(sc.MinTTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid session cache minTTL: %w", err)
+		}
+	}
+	return cache, nil
+}
+
+// WipeSessionCache removes the cache file
+func WipeSessionCache(sc *config.SessionCacheConfig) error {
+	if sc == nil {
+		return fmt.Errorf("no session cache configured")
+	}
+	path, err := sessionCachePath(sc)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func sessionCachePath(sc *config.SessionCacheConfig) (string, error) {
+	if len(sc.Path) > 0 {
+		return sc.Path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".vaultviewer", "sessions"), nil
+}
+
+func sessionKey(vconfig *config.VaultConfig) string {
+	return fmt.Sprintf("%s|%s|%s|%s", vconfig.Address, vconfig.Namespace, vconfig.Name, vconfig.Identity)
+}
+
+// Lookup returns the cached session for the instance, if there is one
+func (sc *SessionCache) Lookup(vconfig *config.VaultConfig) (CachedSession, bool) {
+	if sc == nil {
+		return CachedSession{}, false
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if _, err := os.Stat(sc.path); os.IsNotExist(err) {
+		return CachedSession{}, false
+	}
+	if err := sc.unlock(); err != nil {
+		sc.disable(err)
+		return CachedSession{}, false
+	}
+	session, ok := sc.sessions[sessionKey(vconfig)]
+	return session, ok
+}
+
+// Usable is false for sessions that expire within the configured minimum TTL
+func (sc *SessionCache) Usable(ti TokenInfo) bool {
+	return !ti.Expires() || ti.Remaining() > sc.minTTL
+}
+
+// Store saves the instance token
+func (sc *SessionCache) Store(vi VaultInstance) {
+	if sc == nil {
+		return
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if err := sc.unlock(); err != nil {
+		sc.disable(err)
+		return
+	}
+	sc.sessions[sessionKey(vi.Config)] = CachedSession{
+		Token:      vi.Client.Token(),
+		Accessor:   vi.Token.Accessor,
+		ExpireTime: vi.Token.ExpireTime,
+	}
+	if err := sc.save(); err != nil {
+		log.Printf("unable to save session cache: %v", err)
+	}
+}
+
+// Remove drops a session that is no longer valid
+func (sc *SessionCache) Remove(vconfig *config.VaultConfig) {
+	if sc == nil {
+		return
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if !sc.unlocked {
+		return
+	}
+	delete(sc.sessions, sessionKey(vconfig))
+	if err := sc.save(); err != nil {
+		log.Printf("unable to save session cache: %v", err)
+	}
+}
+
+func (sc *SessionCache) disable(err error) {
+	if !sc.disabled {
+		log.Printf("session cache disabled: %v", err)
+	}
+	sc.disabled = true
+}
+
+// unlock reads the passphrase and decrypts the cache file, once
+func (sc *SessionCache) unlock() error {
+	if sc.disabled {
+		return errors.New("session cache is disabled")
+	}
+	if sc.unlocked {
+		return nil
+	}
+
+	passphrase, err := sc.passphrase()
+	if err != nil {
+		return err
+	}
+
+	sc.sessions = map[string]CachedSession{}
+	contents, err := sc.read()
+	if os.IsNotExist(err) {
+		sc.salt = make([]byte, 16)
+		if _, err := rand.Read(sc.salt); err != nil {
+			return err
+		}
+		sc.key = deriveSessionKey(passphrase, sc.salt)
+		sc.unlocked = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	file := sessionCacheFile{}
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return fmt.Errorf("session cache %s is corrupt: %w", sc.path, err)
+	}
+	sc.salt = file.Salt
+	sc.key = deriveSessionKey(passphrase, sc.salt)
+	gcm, err := newGCM(sc.key)
+	if err != nil {
+		return err
+	}
+	plain, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return fmt.Errorf("unable to decrypt session cache, wrong passphrase?")
+	}
+	if err := json.Unmarshal(plain, &sc.sessions); err != nil {
+		return fmt.Errorf("session cache %s is corrupt: %w", sc.path, err)
+	}
+	sc.unlocked = true
+	return nil
+}
+
+func (sc *SessionCache) passphrase() (string, error) {
+	if len(sc.envVar) > 0 {
+		passphrase := os.Getenv(sc.envVar)
+		if len(passphrase) == 0 {
+			return "", fmt.Errorf("environment variable %s is empty", sc.envVar)
+		}
+		return passphrase, nil
+	}
+	p := sc.prompter
+	if p == nil {
+		p = prompt.NewTerminal()
+	}
+	passphrase, err := prompt.Secret(p, "Session cache", "Passphrase")
+	if err != nil {
+		return "", err
+	}
+	if len(passphrase) == 0 {
+		return "", errors.New("empty passphrase")
+	}
+	return passphrase, nil
+}
+
+// read refuses files other users could read or replace
+func (sc *SessionCache) read() ([]byte, error) {
+	info, err := os.Stat(sc.path)
+	if err != nil {
+		return nil, err
+	}
+	if runtime.GOOS != "windows" {
+		if info.Mode().Perm()&0077 != 0 {
+			return nil, fmt.Errorf("session cache %s has permissions %v, it must only be accessible by its owner (0600)", sc.path, info.Mode().Perm())
+		}
+		dir, err := os.Stat(filepath.Dir(sc.path))
+		if err != nil {
+			return nil, err
+		}
+		if dir.Mode().Perm()&0022 != 0 {
+			return nil, fmt.Errorf("session cache directory %s is writable by others", filepath.Dir(sc.path))
+		}
+	}
+	return os.ReadFile(sc.path)
+}
+
+func (sc *SessionCache) save() error {
+	plain, err := json.Marshal(sc.sessions)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(sc.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	contents, err := json.Marshal(sessionCacheFile{
+		Salt:  sc.salt,
+		Nonce: nonce,
+		Data:  gcm.Seal(nil, nonce, plain, nil),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(sc.path), 0700); err != nil {
+		return err
+	}
+	// write then rename so a crash never leaves half a file, a stale tmp file is
+	// removed first as WriteFile would keep its permissions
+	tmp := sc.path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(contents); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, sc.path)
+}
+
+func deriveSessionKey(passphrase string, salt []byte) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt, sessionCacheIterations, 32, sha256.New)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,28 @@
+package backend
+
+// ConnectionState tracks how far an instance got through connect, login and ACL fetch
+type ConnectionState int
+
+const (
+	StateConnecting ConnectionState = iota
+	StateAuthenticated
+	StateACLLoaded
+	StateFailed
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateAuthenticated:
+		return "authenticated"
+	case StateACLLoaded:
+		return "ACL loaded"
+	case StateFailed:
+		return "failed"
+	}
+	return ""
+}
+
+// StatusFunc is called as an instance moves through the connection states
+type StatusFunc func(state ConnectionState)
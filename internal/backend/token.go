@@ -0,0 +1,191 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// TokenExpiryWarning is how close to expiry a token is flagged
+const TokenExpiryWarning = 5 * time.Minute
+
+// TokenInfo is what lookup-self tells us about the client token
+type TokenInfo struct {
+	Accessor string
+	// the token's own policies, IdentityPolicies come from its entity and groups
+	Policies         []string
+	IdentityPolicies []string
+	Renewable        bool
+	Root             bool
+	TTL              time.Duration
+	ExpireTime       time.Time
+	// empty for tokens without an identity entity, such as root tokens
+	EntityID string
+}
+
+// EffectivePolicies are all the policies the token acts with, its own and those of its identity
+func (ti TokenInfo) EffectivePolicies() []string {
+	return mergeStrings(append([]string{}, ti.Policies...), ti.IdentityPolicies)
+}
+
+// Expires is false for tokens without a TTL, such as root tokens
+func (ti TokenInfo) Expires() bool {
+	return !ti.ExpireTime.IsZero()
+}
+
+func (ti TokenInfo) Remaining() time.Duration {
+	if !ti.Expires() {
+		return 0
+	}
+	remaining := time.Until(ti.ExpireTime)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// ExpiringSoon is true when the token will be gone within TokenExpiryWarning
+func (ti TokenInfo) ExpiringSoon() bool {
+	return ti.Expires() && ti.Remaining() < TokenExpiryWarning
+}
+
+// secret builds the auth secret the lifetime watcher renews
+func (ti TokenInfo) secret(token string) *api.Secret {
+	return &api.Secret{
+		Auth: &api.SecretAuth{
+			ClientToken:   token,
+			Accessor:      ti.Accessor,
+			Policies:      ti.Policies,
+			Renewable:     ti.Renewable,
+			LeaseDuration: int(ti.Remaining().Seconds()),
+		},
+	}
+}
+
+func (vi VaultInstance) LookupToken() (TokenInfo, error) {
+	ti := TokenInfo{}
+	secret, err := vi.Client.Auth().Token().LookupSelf()
+	if err != nil {
+		return ti, fmt.Errorf("unable to look up token: %w", err)
+	}
+	if secret == nil {
+		return ti, fmt.Errorf("unable to look up token: no data returned")
+	}
+
+	ti.Accessor, _ = secret.TokenAccessor()
+	ti.Policies, _ = toStrings(secret.Data["policies"])
+	ti.IdentityPolicies, _ = toStrings(secret.Data["identity_policies"])
+	ti.Renewable, _ = secret.TokenIsRenewable()
+	ti.TTL, _ = secret.TokenTTL()
+	ti.EntityID, _ = secret.Data["entity_id"].(string)
+	for _, p := range ti.EffectivePolicies() {
+		if p == "root" {
+			ti.Root = true
+		}
+	}
+	if ti.TTL > 0 {
+		ti.ExpireTime = time.Now().Add(ti.TTL)
+	}
+	return ti, nil
+}
+
+// TokenWatcher keeps an instance token alive, renewing it while Vault allows
+// and logging in again with the configured auth method once it can't
+type TokenWatcher struct {
+	vi      VaultInstance
+	update  func(TokenInfo, error)
+	relogin func(VaultInstance)
+	stopCh  chan struct{}
+	once    sync.Once
+}
+
+// WatchToken starts watching the instance token. update is called from the
+// watcher goroutine whenever the token is renewed, or when it is lost.
+// After logging in again the ACL and policies are reloaded and handed to relogin.
+func (vi VaultInstance) WatchToken(update func(TokenInfo, error), relogin func(VaultInstance)) *TokenWatcher {
+	tw := &TokenWatcher{
+		vi:      vi,
+		update:  update,
+		relogin: relogin,
+		stopCh:  make(chan struct{}),
+	}
+	go tw.run()
+	return tw
+}
+
+func (tw *TokenWatcher) Stop() {
+	tw.once.Do(func() {
+		close(tw.stopCh)
+	})
+}
+
+func (tw *TokenWatcher) run() {
+	for {
+		// nothing to do for tokens that never expire
+		if !tw.vi.Token.Expires() {
+			return
+		}
+
+		watcher, err := tw.vi.Client.NewLifetimeWatcher(&api.LifetimeWatcherInput{
+			Secret: tw.vi.Token.secret(tw.vi.Client.Token()),
+		})
+		if err != nil {
+			tw.update(tw.vi.Token, err)
+			return
+		}
+		go watcher.Start()
+		done := tw.watch(watcher)
+		watcher.Stop()
+		if !done {
+			return
+		}
+
+		// renewal is no longer possible, log in again
+		log.Printf("%s: token can no longer be renewed, logging in again", tw.vi.DisplayName)
+		relogin, err := tw.vi.Login(tw.vi.Config)
+		if err == nil {
+			relogin.Token, err = relogin.LookupToken()
+		}
+		if err != nil {
+			tw.update(tw.vi.Token, fmt.Errorf("token expired and login failed: %w", err))
+			return
+		}
+		// the new token may carry other policies or another entity
+		relogin, err = relogin.Reload()
+		if err != nil {
+			tw.update(relogin.Token, fmt.Errorf("logged in again but %w", err))
+			return
+		}
+		tw.vi = relogin
+		tw.vi.Cache.Store(tw.vi)
+		tw.relogin(tw.vi)
+	}
+}
+
+// watch passes renewals on until the watcher gives up, false means we were stopped
+func (tw *TokenWatcher) watch(watcher *api.LifetimeWatcher) bool {
+	for {
+		select {
+		case <-tw.stopCh:
+			return false
+		case err := <-watcher.DoneCh():
+			if err != nil {
+				log.Printf("%s: token renewal stopped: %v", tw.vi.DisplayName, err)
+			}
+			return true
+		case renewal := <-watcher.RenewCh():
+			if renewal.Secret == nil || renewal.Secret.Auth == nil {
+				continue
+			}
+			ttl := time.Duration(renewal.Secret.Auth.LeaseDuration) * time.Second
+			tw.vi.Token.TTL = ttl
+			tw.vi.Token.Renewable = renewal.Secret.Auth.Renewable
+			tw.vi.Token.ExpireTime = renewal.RenewedAt.Add(ttl)
+			tw.vi.Cache.Store(tw.vi)
+			tw.update(tw.vi.Token, nil)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+)
+
+// TokenHelper stores the token shared with the vault CLI
+type TokenHelper interface {
+	Get() (string, error)
+	Store(token string) error
+	Erase() error
+}
+
+// NewTokenHelper uses the token_helper from the vault CLI config when one is set,
+// otherwise ~/.vault-token like the CLI does
+func NewTokenHelper(vi VaultInstance) (TokenHelper, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	configPath := os.Getenv("VAULT_CONFIG_PATH")
+	if len(configPath) == 0 {
+		configPath = filepath.Join(home, ".vault")
+	}
+	helperPath, err := readTokenHelperConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(helperPath) == 0 {
+		return &fileTokenHelper{path: filepath.Join(home, ".vault-token")}, nil
+	}
+	// like the CLI, a bare name is looked up on the PATH
+	if !filepath.IsAbs(helperPath) {
+		found, err := exec.LookPath(helperPath)
+		if err != nil {
+			return nil, fmt.Errorf("token_helper %s from %s: %w", helperPath, configPath, err)
+		}
+		helperPath = found
+	}
+
+	env := append(os.Environ(), "VAULT_ADDR="+vi.Config.Address)
+	if len(vi.Config.Namespace) > 0 {
+		env = append(env, "VAULT_NAMESPACE="+vi.Config.Namespace)
+	}
+	return &externalTokenHelper{path: helperPath, env: env}, nil
+}
+
+func readTokenHelperConfig(path string) (string, error) {
+	contents, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to read vault CLI config: %w", err)
+	}
+	cliConfig := struct {
+		TokenHelper string `hcl:"token_helper"`
+	}{}
+	if err := hcl.Decode(&cliConfig, string(contents)); err != nil {
+		return "", fmt.Errorf("unable to parse vault CLI config %s: %w", path, err)
+	}
+	return cliConfig.TokenHelper, nil
+}
+
+// storeToken hands a token obtained by a login to the token helper, unless it already has it
+func (vi VaultInstance) storeToken(token string) error {
+	helper, err := NewTokenHelper(vi)
+	if err != nil {
+		return err
+	}
+	current, err := helper.Get()
+	if err == nil && current == token {
+		return nil
+	}
+	return helper.Store(token)
+}
+
+type fileTokenHelper struct {
+	path string
+}
+
+func (fh *fileTokenHelper) Get() (string, error) {
+	contents, err := os.ReadFile(fh.path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(contents)), nil
+}
+
+func (fh *fileTokenHelper) Store(token string) error {
+	return os.WriteFile(fh.path, []byte(token), 0600)
+}
+
+func (fh *fileTokenHelper) Erase() error {
+	err := os.Remove(fh.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// externalTokenHelper runs the helper program with get, store or erase
+type externalTokenHelper struct {
+	path string
+	env  []string
+}
+
+// run goes through the shell the way the vault CLI does, so helpers given with arguments work the same
+func (eh *externalTokenHelper) run(arg string, stdin string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", eh.path+" "+arg)
+	} else {
+		cmd = exec.Command("/bin/sh", "-c", eh.path+" "+arg)
+	}
+	cmd.Env = eh.env
+	cmd.Stdin = strings.NewReader(stdin)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("token helper %s %s failed: %w: %s", eh.path, arg, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func (eh *externalTokenHelper) Get() (string, error) {
+	out, err := eh.run("get", "")
+	return strings.TrimSpace(out), err
+}
+
+func (eh *externalTokenHelper) Store(token string) error {
+	_, err := eh.run("store", token)
+	return err
+}
+
+func (eh *externalTokenHelper) Erase() error {
+	_, err := eh.run("erase", "")
+	return err
+}
@@ -0,0 +1,119 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around a change
+const diffContext = 3
+
+type diffLine struct {
+	op   byte
+	text string
+	// line numbers in from and to, counting from 1
+	from int
+	to   int
+}
+
+// UnifiedDiff compares two texts line by line, it is empty when they are the same
+func UnifiedDiff(fromName string, toName string, from string, to string) string {
+	a := splitLines(from)
+	b := splitLines(to)
+	lines := diffLines(a, b)
+
+	changes := []int{}
+	for i, l := range lines {
+		if l.op != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for i := 0; i < len(changes); {
+		start := changes[i] - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := changes[i]
+		// changes close enough together share a hunk
+		for i < len(changes) && changes[i] <= end+2*diffContext {
+			end = changes[i]
+			i++
+		}
+		end += diffContext
+		if end > len(lines)-1 {
+			end = len(lines) - 1
+		}
+		hunk := lines[start : end+1]
+
+		fromStart, fromCount, toStart, toCount := 0, 0, 0, 0
+		for _, l := range hunk {
+			if l.op != '+' {
+				if fromCount == 0 {
+					fromStart = l.from
+				}
+				fromCount++
+			}
+			if l.op != '-' {
+				if toCount == 0 {
+					toStart = l.to
+				}
+				toCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", fromStart, fromCount, toStart, toCount)
+		for _, l := range hunk {
+			fmt.Fprintf(&out, "%c%s\n", l.op, l.text)
+		}
+	}
+	return out.String()
+}
+
+func splitLines(text string) []string {
+	if len(text) == 0 {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines walks the longest common subsequence of a and b, policies are small enough for the full table
+func diffLines(a []string, b []string) []diffLine {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] > lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := []diffLine{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{op: ' ', text: a[i], from: i + 1, to: j + 1})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{op: '-', text: a[i], from: i + 1, to: j})
+			i++
+		default:
+			lines = append(lines, diffLine{op: '+', text: b[j], from: i, to: j + 1})
+			j++
+		}
+	}
+	return lines
+}
@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numbered is "line 01" through "line n"
+func numbered(n int) []string {
+	lines := []string{}
+	for i := 1; i <= n; i++ {
+		lines = append(lines, fmt.Sprintf("line %02d", i))
+	}
+	return lines
+}
+
+func text(lines ...string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := numbered(20)
+	replaced := func(at map[int]string) []string {
+		out := append([]string{}, lines...)
+		for i, l := range at {
+			out[i-1] = l
+		}
+		return out
+	}
+
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			name: "identical",
+			from: text(lines...),
+			to:   text(lines...),
+			want: "",
+		},
+		{
+			name: "pure insert",
+			from: text("a", "b", "c"),
+			to:   text("a", "b", "new", "c"),
+			want: "--- from\n+++ to\n@@ -1,3 +1,4 @@\n a\n b\n+new\n c\n",
+		},
+		{
+			name: "pure delete",
+			from: text("a", "b", "gone", "c"),
+			to:   text("a", "b", "c"),
+			want: "--- from\n+++ to\n@@ -1,4 +1,3 @@\n a\n b\n-gone\n c\n",
+		},
+		{
+			name: "empty to text",
+			from: "",
+			to:   text("a", "b"),
+			want: "--- from\n+++ to\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "text to empty",
+			from: text("a", "b"),
+			to:   "",
+			want: "--- from\n+++ to\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name: "no newline at the end",
+			from: "a\nb",
+			to:   "a\nb\n",
+			want: "",
+		},
+		{
+			name: "two changes far apart",
+			from: text(lines...),
+			to:   text(replaced(map[int]string{2: "two", 19: "nineteen"})...),
+			want: "--- from\n+++ to\n" +
+				"@@ -1,5 +1,5 @@\n line 01\n-line 02\n+two\n line 03\n line 04\n line 05\n" +
+				"@@ -16,5 +16,5 @@\n line 16\n line 17\n line 18\n-line 19\n+nineteen\n line 20\n",
+		},
+		{
+			name: "two changes close enough to share a hunk",
+			from: text(lines...),
+			to:   text(replaced(map[int]string{5: "five", 10: "ten"})...),
+			want: "--- from\n+++ to\n" +
+				"@@ -2,12 +2,12 @@\n line 02\n line 03\n line 04\n-line 05\n+five\n line 06\n line 07\n line 08\n line 09\n-line 10\n+ten\n line 11\n line 12\n line 13\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("from", "to", tt.from, tt.to); got != tt.want {
+				t.Errorf("diff =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
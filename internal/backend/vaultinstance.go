@@ -1,207 +1,147 @@
 package backend
 
 import (
-	"bufio"
 	"context"
 	"fmt"
-	"io"
 	"log"
-	"os"
-	"strings"
 
 	"github.com/fennysoftware/vaultviewer/internal/config"
-	"github.com/hashicorp/vault/api"
+	"github.com/fennysoftware/vaultviewer/internal/prompt"
 	vault "github.com/hashicorp/vault/api"
 )
 
 type VaultInstance struct {
-	DisplayName string        `yaml:"-"`
-	Client      *vault.Client `yaml:"-"`
-	Acl         ACL           `yaml:"-"`
+	DisplayName string              `yaml:"-"`
+	Client      *vault.Client       `yaml:"-"`
+	Acl         ACL                 `yaml:"-"`
+	Config      *config.VaultConfig `yaml:"-"`
+	State       ConnectionState     `yaml:"-"`
+	Err         error               `yaml:"-"`
+	Prompter    prompt.Prompter     `yaml:"-"`
+	AuthMethod  string              `yaml:"-"`
+	Token       TokenInfo           `yaml:"-"`
+	Cache       *SessionCache       `yaml:"-"`
+	// the ACL policies of sys/policies/acl, PoliciesErr is set when they couldn't be listed
+	Policies    []*Policy `yaml:"-"`
+	PoliciesErr error     `yaml:"-"`
+	// RGPs and EGPs, SentinelErr is ErrSentinelUnavailable on OSS servers
+	SentinelPolicies []*Policy `yaml:"-"`
+	SentinelErr      error     `yaml:"-"`
+	// whether sys/health reported an Enterprise build, nil until it was asked
+	Enterprise *bool `yaml:"-"`
+	// the token's entity for resolving templated policies, EntityErr says why it's missing
+	Entity    *Identity `yaml:"-"`
+	EntityErr error     `yaml:"-"`
+	// built from local policy files by NewSandbox, never connected
+	Sandbox bool `yaml:"-"`
+	// templated rule paths a simulation left out because placeholders couldn't be resolved
+	Unresolved []string `yaml:"-"`
 }
 
-func ConnectVaultInstance(vconfig *config.VaultConfig) (VaultInstance, error) {
-	config := vault.DefaultConfig()
-	config.Address = vconfig.Address
-	client, err := vault.NewClient(config)
-	if len(vconfig.Namespace) > 0 {
-		client.SetNamespace(vconfig.Namespace)
-	}
+// NewVaultInstance returns an unconnected instance so it can be shown before the connection is made
+func NewVaultInstance(vconfig *config.VaultConfig) VaultInstance {
 	vi := VaultInstance{}
-
+	vi.Config = vconfig
+	vi.State = StateConnecting
 	if len(vconfig.Name) == 0 {
-		vi.DisplayName = client.Address() + " - " + client.Namespace()
+		vi.DisplayName = vconfig.Address + " - " + vconfig.Namespace
 	} else {
 		vi.DisplayName = vconfig.Name
 	}
+	vi.DisplayName += identitySuffix(vconfig)
+	return vi
+}
 
-	if err != nil {
-		return vi, err
+func identitySuffix(vconfig *config.VaultConfig) string {
+	if len(vconfig.Identity) == 0 {
+		return ""
 	}
+	return " as " + vconfig.Identity
+}
 
-	vi.Client = client
-	return vi, nil
+// InsecureTLS is true when certificate verification is switched off for the instance
+func (vi VaultInstance) InsecureTLS() bool {
+	return vi.Config != nil && vi.Config.TLS != nil && vi.Config.TLS.Insecure
 }
 
-func (vi VaultInstance) Login(vconfig *config.VaultConfig) (VaultInstance, error) {
-	if vconfig.Auth == nil {
-		reader := bufio.NewReader(os.Stdin)
-		fmt.Printf("Enter Username: ")
-		text, err := reader.ReadString('\n')
-		if err != nil {
-			return vi, err
-		}
-		username := strings.Replace(text, "\n", "", -1)
+func ConnectVaultInstance(vconfig *config.VaultConfig) (VaultInstance, error) {
+	vi := NewVaultInstance(vconfig)
 
-		fmt.Printf("Enter Password for %s: ", username)
-		text, err = reader.ReadString('\n')
-		if err != nil {
-			return vi, err
+	config := vault.DefaultConfig()
+	config.Address = vconfig.Address
+	if vconfig.TLS != nil {
+		if vconfig.TLS.Insecure {
+			log.Printf("WARNING: %s: TLS certificate verification is disabled", vi.DisplayName)
 		}
-		pwd := strings.Replace(text, "\n", "", -1)
-
-		login, err := vi.loginUsernamePassword(username, pwd)
+		err := config.ConfigureTLS(&vault.TLSConfig{
+			CACert:        vconfig.TLS.CACert,
+			CAPath:        vconfig.TLS.CAPath,
+			ClientCert:    vconfig.TLS.ClientCert,
+			ClientKey:     vconfig.TLS.ClientKey,
+			TLSServerName: vconfig.TLS.ServerName,
+			Insecure:      vconfig.TLS.Insecure,
+		})
 		if err != nil {
-			return vi, err
-		}
-		vi.Client.SetToken(login.Auth.ClientToken)
-		return vi, nil
-	} else {
-		// token first
-		// it's easy
-		if len(vconfig.Auth.Token) > 0 {
-			vi.Client.SetToken(vconfig.Auth.Token)
-			return vi, nil
-		} else {
-			if len(vconfig.Auth.JWT) > 0 {
-				login, err := vi.loginJWT(vconfig.Auth.JWT)
-				if err != nil {
-					return vi, err
-				}
-				vi.Client.SetToken(login.Auth.ClientToken)
-			}
-
-			if vconfig.Auth.LDAP != nil {
-				login, err := vi.loginLDAP(vconfig.Auth.LDAP)
-				if err != nil {
-					return vi, err
-				}
-				vi.Client.SetToken(login.Auth.ClientToken)
-			}
-			if len(vconfig.Auth.Username) > 0 {
-				pwd := vconfig.Auth.Password
-				if len(pwd) == 0 {
-					reader := bufio.NewReader(os.Stdin)
-					fmt.Printf("Enter Password for %s: ", vconfig.Auth.Username)
-					text, err := reader.ReadString('\n')
-					if err != nil {
-						return vi, err
-					}
-					pwd = strings.Replace(text, "\n", "", -1)
-				}
-				login, err := vi.loginUsernamePassword(vconfig.Auth.Username, pwd)
-				if err != nil {
-					return vi, err
-				}
-				vi.Client.SetToken(login.Auth.ClientToken)
-			}
-			return vi, nil
+			return vi, fmt.Errorf("unable to configure TLS: %w", err)
 		}
 	}
-}
-
-func (vi VaultInstance) loginLDAP(a *config.LDAPAuth) (*api.Secret, error) {
-
-	loginData := make(map[string]interface{})
-
-	if a.PasswordFile != "" {
-		passwordValue, err := readPasswordFromFile(a.PasswordFile)
-		if err != nil {
-			return nil, fmt.Errorf("error reading password: %w", err)
-		}
-		loginData["password"] = passwordValue
-	} else if a.PasswordEnv != "" {
-		passwordValue := os.Getenv(a.PasswordEnv)
-		if passwordValue == "" {
-			return nil, fmt.Errorf("password was specified with an environment variable with an empty value")
-		}
-		loginData["password"] = passwordValue
-	} else {
-		pwd := a.Password
-		if len(pwd) == 0 {
-			reader := bufio.NewReader(os.Stdin)
-			fmt.Printf("Enter Password for %s: ", a.Username)
-			text, err := reader.ReadString('\n')
-			if err != nil {
-				return nil, err
-			}
-			pwd = strings.Replace(text, "\n", "", -1)
-
-		}
-		loginData["password"] = pwd
+	client, err := vault.NewClient(config)
+	if err != nil {
+		return vi, err
 	}
-
-	mp := a.MountPath
-	if len(mp) == 0 {
-		mp = "ldap"
+	if len(vconfig.Namespace) > 0 {
+		client.SetNamespace(vconfig.Namespace)
 	}
 
-	path := fmt.Sprintf("auth/%s/login/%s", mp, a.Username)
-	ctx := context.Background()
-
-	login, err := vi.Client.Logical().WriteWithContext(ctx, path, loginData)
-	if err != nil {
-		return nil, fmt.Errorf("unable to log in with LDAP auth: %w", err)
+	if len(vconfig.Name) == 0 {
+		vi.DisplayName = client.Address() + " - " + client.Namespace() + identitySuffix(vconfig)
 	}
-	return login, nil
+
+	vi.Client = client
+	return vi, nil
 }
 
-func readPasswordFromFile(path string) (string, error) {
-	passwordFile, err := os.Open(path)
+// Login dispatches to the configured auth method and sets the client token
+func (vi VaultInstance) Login(vconfig *config.VaultConfig) (VaultInstance, error) {
+	method, auth, err := NewAuthenticator(vconfig.Auth)
 	if err != nil {
-		return "", fmt.Errorf("unable to open file containing password: %w", err)
+		return vi, err
 	}
-	defer passwordFile.Close()
 
-	limitedReader := io.LimitReader(passwordFile, 1000)
-	passwordBytes, err := io.ReadAll(limitedReader)
+	login, err := auth.Login(vi)
 	if err != nil {
-		return "", fmt.Errorf("unable to read password: %w", err)
+		return vi, err
 	}
-
-	passwordValue := strings.TrimSuffix(string(passwordBytes), "\n")
-
-	return passwordValue, nil
-}
-
-func (vi VaultInstance) loginUsernamePassword(U string, P string) (*api.Secret, error) {
-	// to pass the password
-	options := map[string]interface{}{
-		"password": P,
+	if login != nil && login.Auth != nil && login.Auth.MFARequirement != nil {
+		login, err = vi.completeMFA(login)
+		if err != nil {
+			return vi, err
+		}
 	}
+	if login == nil || login.Auth == nil || len(login.Auth.ClientToken) == 0 {
+		return vi, fmt.Errorf("%s login at auth/%s returned no client token", method, auth.MountPath())
+	}
+	vi.Client.SetToken(login.Auth.ClientToken)
+	vi.AuthMethod = method
 
-	// the login path
-	path := fmt.Sprintf("auth/userpass/login/%s", U)
-
-	// PUT call to get a token
-	login, err := vi.Client.Logical().Write(path, options)
-	if err != nil {
-		return nil, err
+	// share the session with the vault CLI, tokens that came from it or the config stay where they are
+	if r, ok := auth.(tokenReuser); ok && r.reused() {
+		return vi, nil
+	}
+	if vconfig.StoreToken {
+		if err := vi.storeToken(login.Auth.ClientToken); err != nil {
+			log.Printf("%s: unable to store token with the token helper: %v", vi.DisplayName, err)
+		}
 	}
-	return login, nil
+	return vi, nil
 }
 
-func (vi VaultInstance) loginJWT(JWT string) (*api.Secret, error) {
-	// to pass the password
-	options := map[string]interface{}{
-		"jwt": JWT,
-	}
-	// PUT call to get a token
-	login, err := vi.Client.Logical().Write("auth/jwt/login", options)
-	if err != nil {
-		return nil, err
+// prompter falls back to the terminal when no prompter was given
+func (vi VaultInstance) prompter() prompt.Prompter {
+	if vi.Prompter == nil {
+		return prompt.NewTerminal()
 	}
-	return login, nil
+	return vi.Prompter
 }
 
 func (vi VaultInstance) GetACL() (VaultInstance, error) {
@@ -219,54 +159,105 @@ func (vi VaultInstance) GetACL() (VaultInstance, error) {
 	} else {
 		if resultant_acl != nil {
 
-			log.Printf("%v\n", resultant_acl.Data)
 			for k, v := range resultant_acl.Data {
 				switch k {
-				case "glob_paths":
-					ips := v.(map[string]interface{})
-					ep := toPathPermissions(ips)
-					if ep != nil {
-						vi.Acl.PrefixRules = ep
+				case "glob_paths", "exact_paths":
+					ips, ok := v.(map[string]interface{})
+					if !ok {
+						vi.Acl.ParseErrors = append(vi.Acl.ParseErrors, &ACLParseError{Key: k, Expected: "object", Got: v})
+						continue
 					}
-				case "exact_paths":
-					ips := v.(map[string]interface{})
-					ep := toPathPermissions(ips)
-					if ep != nil {
+					ep, errs := toPathPermissions(ips)
+					vi.Acl.ParseErrors = append(vi.Acl.ParseErrors, errs...)
+					if k == "glob_paths" {
+						vi.Acl.PrefixRules = ep
+					} else {
 						vi.Acl.ExactRules = ep
 					}
 				case "root":
-					ips := v.(bool)
+					ips, ok := v.(bool)
+					if !ok {
+						vi.Acl.ParseErrors = append(vi.Acl.ParseErrors, &ACLParseError{Key: k, Expected: "bool", Got: v})
+						continue
+					}
 					vi.Acl.Root = ips
-				default:
-					log.Printf("\n%s:%v\n", k, v)
 				}
 			}
-			log.Printf("\nACL:\n\n%v\n", vi.Acl)
+			vi.Acl.SplitSegmentWildcards()
+			for _, err := range vi.Acl.ParseErrors {
+				log.Printf("%s: %v", vi.DisplayName, err)
+			}
 		}
 	}
 	return vi, nil
 }
 
-func BuildAndConnect(vconfig *config.VaultConfig) (VaultInstance, error) {
+// BuildAndConnect connects, logs in and fetches the ACL, reporting progress through status.
+// On failure the returned instance is in StateFailed with Err set.
+func BuildAndConnect(vconfig *config.VaultConfig, p prompt.Prompter, cache *SessionCache, status StatusFunc) (VaultInstance, error) {
+	if status == nil {
+		status = func(ConnectionState) {}
+	}
+	status(StateConnecting)
+
 	vi, err := ConnectVaultInstance(vconfig)
+	vi.Prompter = p
+	vi.Cache = cache
 	if err != nil {
-		log.Printf("unable to initialize Vault client: %v", err)
-		return vi, err
+		return vi.failed(fmt.Errorf("unable to initialize Vault client: %w", err))
 	} else {
 
-		update, err := vi.Login(vconfig)
-		if err != nil {
-			log.Printf("unable to login: %v", err)
-			return vi, err
+		if !vi.resumeSession() {
+			update, err := vi.Login(vconfig)
+			if err != nil {
+				return vi.failed(fmt.Errorf("unable to login: %w", err))
+			}
+			vi = update
+			vi.Token, err = vi.LookupToken()
+			if err != nil {
+				return vi.failed(err)
+			}
+			vi.Cache.Store(vi)
 		}
-		vi = update
-		update, err = vi.GetACL()
+		vi.State = StateAuthenticated
+		status(vi.State)
+
+		update, err := vi.GetACL()
 		if err != nil {
-			log.Printf("unable to get ACL: %v", err)
-			return vi, err
+			return vi.failed(fmt.Errorf("unable to get ACL: %w", err))
 		}
 		vi = update
+
+		vi = vi.loadPolicies()
+		vi.State = StateACLLoaded
+		status(vi.State)
 	}
 
 	return vi, nil
 }
+
+// resumeSession uses the cached token when lookup-self still accepts it and it isn't close to expiry
+func (vi *VaultInstance) resumeSession() bool {
+	session, ok := vi.Cache.Lookup(vi.Config)
+	if !ok {
+		return false
+	}
+	vi.Client.SetToken(session.Token)
+	ti, err := vi.LookupToken()
+	if err != nil || !vi.Cache.Usable(ti) {
+		log.Printf("%s: cached session is no longer usable", vi.DisplayName)
+		vi.Cache.Remove(vi.Config)
+		vi.Client.ClearToken()
+		return false
+	}
+	vi.Token = ti
+	vi.AuthMethod = "session cache"
+	return true
+}
+
+func (vi VaultInstance) failed(err error) (VaultInstance, error) {
+	log.Printf("%s: %v", vi.DisplayName, err)
+	vi.State = StateFailed
+	vi.Err = err
+	return vi, err
+}
@@ -0,0 +1,66 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// unwrap exchanges a response-wrapping token using a copy of the client,
+// so the wrapping token never replaces the instance token
+func (vi VaultInstance) unwrap(wrappingToken string) (*api.Secret, error) {
+	client, err := vi.Client.CloneWithHeaders()
+	if err != nil {
+		return nil, err
+	}
+	client.SetToken(strings.TrimSpace(wrappingToken))
+
+	secret, err := client.Logical().Unwrap("")
+	if err != nil {
+		return nil, fmt.Errorf("unable to unwrap: %w", err)
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("unable to unwrap: no data returned")
+	}
+	return secret, nil
+}
+
+// WrapInfo is what sys/wrapping/lookup says about a wrapping token
+type WrapInfo struct {
+	CreationPath string
+	CreationTime time.Time
+	CreationTTL  time.Duration
+}
+
+// lookupWrapping needs no token of our own, the wrapping token goes in the body
+func (vi VaultInstance) lookupWrapping(wrappingToken string) (WrapInfo, error) {
+	wi := WrapInfo{}
+	client, err := vi.Client.CloneWithHeaders()
+	if err != nil {
+		return wi, err
+	}
+	client.ClearToken()
+
+	secret, err := client.Logical().Write("sys/wrapping/lookup", map[string]interface{}{
+		"token": strings.TrimSpace(wrappingToken),
+	})
+	if err != nil {
+		return wi, fmt.Errorf("unable to look up wrapping token: %w", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return wi, fmt.Errorf("unable to look up wrapping token: no data returned")
+	}
+
+	wi.CreationPath, _ = secret.Data["creation_path"].(string)
+	if created, ok := secret.Data["creation_time"].(string); ok {
+		wi.CreationTime, _ = time.Parse(time.RFC3339Nano, created)
+	}
+	if ttl, ok := secret.Data["creation_ttl"].(json.Number); ok {
+		seconds, _ := ttl.Int64()
+		wi.CreationTTL = time.Duration(seconds) * time.Second
+	}
+	return wi, nil
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,23 +16,185 @@ type LDAPAuth struct {
 	PasswordEnv  string `yaml:"passwordEnv"`
 }
 
+type UserpassAuth struct {
+	MountPath string `yaml:"mountPath"`
+	Username  string `yaml:"username"`
+	Password  string `yaml:"password"`
+}
+
+type JWTAuth struct {
+	MountPath string `yaml:"mountPath"`
+	Role      string `yaml:"role"`
+	JWT       string `yaml:"jwt"`
+}
+
+type AppRoleAuth struct {
+	MountPath    string `yaml:"mountPath"`
+	RoleID       string `yaml:"roleId"`
+	RoleIDFile   string `yaml:"roleIdFile"`
+	SecretID     string `yaml:"secretId"`
+	SecretIDFile string `yaml:"secretIdFile"`
+	SecretIDEnv  string `yaml:"secretIdEnv"`
+	// the secret id is a response-wrapping token to unwrap first
+	SecretIDWrapped bool `yaml:"secretIdWrapped"`
+}
+
+type WrappedTokenAuth struct {
+	// prompted for when neither is set
+	Token     string `yaml:"token"`
+	TokenFile string `yaml:"tokenFile"`
+	// the token is refused unless it was wrapped by this path, e.g. auth/token/create
+	ExpectedCreationPath string `yaml:"expectedCreationPath"`
+}
+
+type TokenAuth struct {
+	Token string `yaml:"token"`
+}
+
+// VaultAuth holds the config block of each configured auth method keyed by the method name.
+// The blocks are decoded by the authenticator registered for the method.
 type VaultAuth struct {
-	LDAP     *LDAPAuth `yaml:"ldap"`
-	Username string    `yaml:"user"`
-	Password string    `yaml:"password"`
-	Token    string    `yaml:"token"`
-	JWT      string    `yaml:"jwt"`
+	Methods map[string]*yaml.Node `yaml:"-"`
+}
+
+// UnmarshalYAML keeps each method block as a raw node.
+// The older flat user/password keys are folded into a userpass block.
+func (a *VaultAuth) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: auth must be a mapping of auth methods", value.Line)
+	}
+
+	a.Methods = map[string]*yaml.Node{}
+	var user, password *yaml.Node
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key := value.Content[i]
+		block := value.Content[i+1]
+		switch key.Value {
+		case "user":
+			user = block
+		case "password":
+			password = block
+		default:
+			if _, ok := a.Methods[key.Value]; ok {
+				return fmt.Errorf("line %d: auth method %q configured twice", key.Line, key.Value)
+			}
+			a.Methods[key.Value] = block
+		}
+	}
+
+	if user != nil || password != nil {
+		if _, ok := a.Methods["userpass"]; ok {
+			return fmt.Errorf("line %d: auth has both user/password and a userpass block", value.Line)
+		}
+		block := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if user != nil {
+			block.Content = append(block.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "username"}, user)
+		}
+		if password != nil {
+			block.Content = append(block.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "password"}, password)
+		}
+		a.Methods["userpass"] = block
+	}
+	return nil
+}
+
+type KubernetesAuth struct {
+	MountPath string `yaml:"mountPath"`
+	Role      string `yaml:"role"`
+	// defaults to the pod's service account token
+	TokenPath string `yaml:"tokenPath"`
+}
+
+type OIDCAuth struct {
+	MountPath string `yaml:"mountPath"`
+	Role      string `yaml:"role"`
+	// the local callback listener, registered in the role's allowed_redirect_uris
+	CallbackHost string `yaml:"callbackHost"`
+	CallbackPort int    `yaml:"callbackPort"`
+	// how long to wait for the browser login, e.g. 2m
+	Timeout     string `yaml:"timeout"`
+	SkipBrowser bool   `yaml:"skipBrowser"`
+}
+
+type CertAuth struct {
+	MountPath string `yaml:"mountPath"`
+	// optional certificate role to log in against
+	Name string `yaml:"name"`
+}
+
+type TLSConfig struct {
+	CACert     string `yaml:"caCert"`
+	CAPath     string `yaml:"caPath"`
+	ClientCert string `yaml:"clientCert"`
+	ClientKey  string `yaml:"clientKey"`
+	ServerName string `yaml:"serverName"`
+	// disables certificate verification, only for testing
+	Insecure bool `yaml:"insecure"`
+}
+
+// AuthProfile is a named identity to log in to an instance with
+type AuthProfile struct {
+	Name string     `yaml:"name"`
+	Auth *VaultAuth `yaml:"auth"`
 }
 
 type VaultConfig struct {
 	Name      string     `yaml:"name"`
 	Address   string     `yaml:"url"`
 	Namespace string     `yaml:"namespace"`
+	TLS       *TLSConfig `yaml:"tls"`
 	Auth      *VaultAuth `yaml:"auth"`
+	// store tokens from logins with the vault CLI token helper
+	StoreToken bool `yaml:"storeToken"`
+	// policies can be viewed but not changed
+	ReadOnly bool `yaml:"readOnly"`
+	// several identities for the same instance, instead of auth
+	Profiles []*AuthProfile `yaml:"profiles"`
+	// set on the per profile copies made by Identities
+	Identity string `yaml:"-"`
+}
+
+// Identities returns a copy of the config for every auth profile, or the config itself when there are none
+func (vc *VaultConfig) Identities() []*VaultConfig {
+	if len(vc.Profiles) == 0 {
+		return []*VaultConfig{vc}
+	}
+	identities := []*VaultConfig{}
+	for _, p := range vc.Profiles {
+		identity := *vc
+		identity.Auth = p.Auth
+		identity.Profiles = nil
+		identity.Identity = p.Name
+		identities = append(identities, &identity)
+	}
+	return identities
+}
+
+type SessionCacheConfig struct {
+	// defaults to ~/.vaultviewer/sessions
+	Path string `yaml:"path"`
+	// read the passphrase from this variable instead of prompting
+	PassphraseEnv string `yaml:"passphraseEnv"`
+	// cached tokens with less than this left are replaced by a new login, e.g. 10m
+	MinTTL string `yaml:"minTTL"`
+}
+
+// LintSuppression hides findings, empty fields match anything and a path ending in * matches by prefix
+type LintSuppression struct {
+	Rule   string `yaml:"rule"`
+	Policy string `yaml:"policy"`
+	Path   string `yaml:"path"`
+	Reason string `yaml:"reason"`
+}
+
+type LintConfig struct {
+	Suppress []LintSuppression `yaml:"suppress"`
 }
 
 type VaultInstanceConfig struct {
-	Instances []*VaultConfig `yaml:"instances"`
+	Instances    []*VaultConfig      `yaml:"instances"`
+	SessionCache *SessionCacheConfig `yaml:"sessionCache"`
+	Lint         *LintConfig         `yaml:"lint"`
 }
 
 func LoadConfig(path string) (VaultInstanceConfig, error) {
@@ -45,5 +209,37 @@ func LoadConfig(path string) (VaultInstanceConfig, error) {
 	if err != nil {
 		return vic, err
 	}
+	// the vault CLI token helper holds a single token
+	storing := []string{}
+	for _, vc := range vic.Instances {
+		if vc.StoreToken {
+			name := vc.Name
+			if len(name) == 0 {
+				name = vc.Address
+			}
+			storing = append(storing, name)
+			if len(vc.Profiles) > 1 {
+				return vic, fmt.Errorf("instance %s: storeToken can't be used with several profiles, the token helper holds a single token", name)
+			}
+		}
+	}
+	if len(storing) > 1 {
+		return vic, fmt.Errorf("storeToken is set on %s, only one instance can share its token with the vault CLI", strings.Join(storing, ", "))
+	}
+	for _, vc := range vic.Instances {
+		if len(vc.Profiles) > 0 && vc.Auth != nil {
+			return vic, fmt.Errorf("instance %s: use either auth or profiles, not both", vc.Name)
+		}
+		names := map[string]bool{}
+		for _, p := range vc.Profiles {
+			if len(p.Name) == 0 {
+				return vic, fmt.Errorf("instance %s: every profile needs a name", vc.Name)
+			}
+			if names[p.Name] {
+				return vic, fmt.Errorf("instance %s: profile %s is defined twice", vc.Name, p.Name)
+			}
+			names[p.Name] = true
+		}
+	}
 	return vic, nil
 }
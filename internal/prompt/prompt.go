@@ -0,0 +1,69 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var ErrCancelled = errors.New("prompt cancelled")
+
+// Field is a single value asked of the user, Value is used to prefill it
+type Field struct {
+	Label  string
+	Value  string
+	Secret bool
+}
+
+// Prompter asks the user for values, the answers are returned in field order
+type Prompter interface {
+	Ask(title string, fields []Field) ([]string, error)
+}
+
+// Notifier shows a message without waiting for an answer
+type Notifier interface {
+	Notify(title string, message string)
+}
+
+// Notify uses the prompter's Notifier when it has one, otherwise writes to stderr
+func Notify(p Prompter, title string, message string) {
+	if n, ok := p.(Notifier); ok {
+		n.Notify(title, message)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s\n%s\n", title, message)
+}
+
+// Credentials asks for a username and password, the username is prefilled when known
+func Credentials(p Prompter, title string, username string) (string, string, error) {
+	answers, err := p.Ask(title, []Field{
+		{Label: "Username", Value: username},
+		{Label: "Password", Secret: true},
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return answers[0], answers[1], nil
+}
+
+// Secret asks for a single masked value
+func Secret(p Prompter, title string, label string) (string, error) {
+	answers, err := p.Ask(title, []Field{
+		{Label: label, Secret: true},
+	})
+	if err != nil {
+		return "", err
+	}
+	return answers[0], nil
+}
+
+// Value asks for a single clear text value
+func Value(p Prompter, title string, label string, value string) (string, error) {
+	answers, err := p.Ask(title, []Field{
+		{Label: label, Value: value},
+	})
+	if err != nil {
+		return "", err
+	}
+	return answers[0], nil
+}
@@ -0,0 +1,69 @@
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// Terminal prompts on stdin/stderr without echoing secrets, for use without the TUI
+type Terminal struct {
+	mu     sync.Mutex
+	reader *bufio.Reader
+}
+
+func NewTerminal() *Terminal {
+	return &Terminal{reader: bufio.NewReader(os.Stdin)}
+}
+
+func (t *Terminal) Ask(title string, fields []Field) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fmt.Fprintf(os.Stderr, "%s\n", title)
+	answers := []string{}
+	for _, f := range fields {
+		if len(f.Value) > 0 && !f.Secret {
+			fmt.Fprintf(os.Stderr, "%s [%s]: ", f.Label, f.Value)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: ", f.Label)
+		}
+
+		text, err := t.read(f.Secret)
+		if err != nil {
+			return nil, err
+		}
+		if len(text) == 0 {
+			text = f.Value
+		}
+		answers = append(answers, text)
+	}
+	return answers, nil
+}
+
+func (t *Terminal) read(secret bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if secret && term.IsTerminal(fd) {
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+	text, err := t.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(text, "\r\n"), nil
+}
+
+func (t *Terminal) Notify(title string, message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(os.Stderr, "%s\n%s\n", title, message)
+}
@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fennysoftware/vaultviewer/internal/backend"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const diffPage = "diff"
+
+// markCompare remembers the first instance, the second one opens the diff view
+func (vwr *Viewer) markCompare(ref *TNodeRef) {
+	if ref == nil || ref.Instance == nil || ref.Instance.State != backend.StateACLLoaded {
+		vwr.showText("Select a connected instance to compare")
+		return
+	}
+	if vwr.compareFrom == nil || vwr.compareFrom == ref.Instance {
+		vwr.compareFrom = ref.Instance
+		vwr.showText(fmt.Sprintf("Comparing from %s\n\nPress d on another instance or identity to see the differences", ref.Instance.DisplayName))
+		return
+	}
+	left := vwr.compareFrom
+	vwr.compareFrom = nil
+	vwr.showDiff(backend.DiffACL(left, ref.Instance))
+}
+
+// showDiff lays the two sides next to each other, Esc closes it
+func (vwr *Viewer) showDiff(diff backend.ACLDiff) {
+	previous := vwr.app.GetFocus()
+	table := tview.NewTable().SetFixed(1, 0).SetSelectable(true, false)
+	table.SetBorder(true).SetTitle(fmt.Sprintf("ACL diff: %s -> %s (Esc to close)", diff.Left, diff.Right))
+
+	header := func(col int, text string) {
+		table.SetCell(0, col, tview.NewTableCell(tview.Escape(text)).SetSelectable(false).SetTextColor(tcell.ColorYellow))
+	}
+	header(0, "")
+	header(1, "Path")
+	header(2, "Rule")
+	header(3, diff.Left)
+	header(4, diff.Right)
+
+	row := 1
+	if diff.LeftRoot != diff.RightRoot {
+		table.SetCell(row, 0, tview.NewTableCell("~").SetTextColor(tcell.ColorOrange))
+		table.SetCell(row, 1, tview.NewTableCell("(root policy)"))
+		table.SetCell(row, 3, tview.NewTableCell(fmt.Sprint(diff.LeftRoot)))
+		table.SetCell(row, 4, tview.NewTableCell(fmt.Sprint(diff.RightRoot)))
+		row++
+	}
+	for _, pd := range diff.Paths {
+		marker, col := "~", tcell.ColorOrange
+		switch pd.Change {
+		case backend.PathAdded:
+			marker, col = "+", tcell.ColorGreen
+		case backend.PathRemoved:
+			marker, col = "-", tcell.ColorRed
+		}
+		table.SetCell(row, 0, tview.NewTableCell(marker).SetTextColor(col))
+		table.SetCell(row, 1, tview.NewTableCell(tview.Escape(pd.Path)).SetTextColor(col))
+		table.SetCell(row, 2, tview.NewTableCell(pd.RuleType))
+		table.SetCell(row, 3, tview.NewTableCell(strings.Join(pd.Left, ", ")))
+		table.SetCell(row, 4, tview.NewTableCell(strings.Join(pd.Right, ", ")))
+		row++
+	}
+	if row == 1 {
+		table.SetCell(row, 1, tview.NewTableCell("no differences"))
+	}
+
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			vwr.pages.RemovePage(diffPage)
+			vwr.app.SetFocus(previous)
+		}
+	})
+	vwr.pages.AddPage(diffPage, table, true, true)
+	vwr.app.SetFocus(table)
+	vwr.showText(diff.Text())
+}
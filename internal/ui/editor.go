@@ -0,0 +1,331 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/fennysoftware/vaultviewer/internal/backend"
+	"github.com/fennysoftware/vaultviewer/internal/prompt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	editorPage  = "editor"
+	previewPage = "preview"
+	namePage    = "name"
+)
+
+// new policies start from a commented example
+const policyTemplate = `# path "secret/data/example/*" {
+#   capabilities = ["read", "list"]
+# }
+`
+
+// editableInstance is the connected instance behind a Policies or policy node
+func (vwr *Viewer) editableInstance(ref *TNodeRef) *backend.VaultInstance {
+	if ref == nil || (ref.Type != 7 && ref.Type != 8) || ref.Instance == nil || ref.Instance.State != backend.StateACLLoaded {
+		vwr.showText("Select a policy, or Policies to create one")
+		return nil
+	}
+	if ref.Instance.Offline() {
+		vwr.showText("Sandbox policies are edited in their files, load them again to see the changes")
+		return nil
+	}
+	return ref.Instance
+}
+
+// editPolicy opens the selected policy in the TextArea editor, or in $EDITOR when external is set
+func (vwr *Viewer) editPolicy(ref *TNodeRef, external bool) {
+	vi := vwr.editableInstance(ref)
+	if vi == nil {
+		return
+	}
+	if ref.Type != 8 {
+		vwr.newPolicy(vi, external)
+		return
+	}
+	if ref.Policy.Err != nil && len(ref.Policy.Raw) == 0 {
+		vwr.showText(fmt.Sprintf("Policy %s could not be read: %v", ref.Policy.Name, ref.Policy.Err))
+		return
+	}
+	vwr.openEditor(vi, ref.Policy.Name, ref.Policy.Raw, external)
+}
+
+// newPolicy asks for the name of the policy to create
+func (vwr *Viewer) newPolicy(vi *backend.VaultInstance, external bool) {
+	previous := vwr.app.GetFocus()
+	input := tview.NewInputField().SetLabel("Name: ")
+	input.SetBorder(true).SetTitle("New policy on " + vi.DisplayName)
+	input.SetDoneFunc(func(key tcell.Key) {
+		vwr.pages.RemovePage(namePage)
+		vwr.app.SetFocus(previous)
+		name := strings.TrimSpace(input.GetText())
+		if key != tcell.KeyEnter || len(name) == 0 {
+			return
+		}
+		for _, p := range vi.Policies {
+			if p.Name == name {
+				vwr.showText(fmt.Sprintf("Policy %s already exists, edit it instead", name))
+				return
+			}
+		}
+		vwr.openEditor(vi, name, policyTemplate, external)
+	})
+	vwr.pages.AddPage(namePage, modal(input, 60, 3), true, true)
+	vwr.app.SetFocus(input)
+}
+
+func (vwr *Viewer) openEditor(vi *backend.VaultInstance, name string, rules string, external bool) {
+	if external {
+		vwr.externalEditor(vi, name, rules)
+	} else {
+		vwr.textEditor(vi, name, rules)
+	}
+}
+
+// textEditor edits the policy in place, Ctrl-S validates and previews, Esc cancels
+func (vwr *Viewer) textEditor(vi *backend.VaultInstance, name string, rules string) {
+	previous := vwr.app.GetFocus()
+	area := tview.NewTextArea().SetText(rules, false)
+	title := fmt.Sprintf("Policy %s on %s (Ctrl-S to preview, Esc to cancel)", name, vi.DisplayName)
+	if vi.ReadOnly() {
+		title = fmt.Sprintf("Policy %s on %s, READ-ONLY (Esc to close)", name, vi.DisplayName)
+	}
+	area.SetBorder(true).SetTitle(title)
+	area.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			vwr.pages.RemovePage(editorPage)
+			vwr.app.SetFocus(previous)
+			return nil
+		case tcell.KeyCtrlS:
+			text := area.GetText()
+			vwr.previewPolicy(vi, name, text, func() {
+				vwr.app.SetFocus(area)
+			}, func() {
+				vwr.pages.RemovePage(editorPage)
+				vwr.app.SetFocus(previous)
+			})
+			return nil
+		}
+		return event
+	})
+	vwr.pages.AddPage(editorPage, area, true, true)
+	vwr.app.SetFocus(area)
+}
+
+// externalEditor suspends the TUI and edits a temporary copy of the policy with $EDITOR
+func (vwr *Viewer) externalEditor(vi *backend.VaultInstance, name string, rules string) {
+	var text string
+	var err error
+	vwr.app.Suspend(func() {
+		text, err = runEditor(name, rules)
+	})
+	if err != nil {
+		prompt.Notify(vwr.prompter, "Editor failed", err.Error())
+		return
+	}
+	if text == rules {
+		vwr.showText(fmt.Sprintf("Policy %s was not changed", name))
+		return
+	}
+	previous := vwr.app.GetFocus()
+	vwr.previewPolicy(vi, name, text, func() {
+		vwr.pages.RemovePage(previewPage)
+		vwr.externalEditor(vi, name, text)
+	}, func() {
+		vwr.app.SetFocus(previous)
+	})
+}
+
+func runEditor(name string, rules string) (string, error) {
+	editor := os.Getenv("EDITOR")
+	if len(editor) == 0 {
+		editor = "vi"
+	}
+	f, err := os.CreateTemp("", "vaultviewer-"+name+"-*.hcl")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(rules); err != nil {
+		f.Close()
+		return "", err
+	}
+	f.Close()
+
+	// EDITOR may carry arguments, e.g. "code --wait"
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], f.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %w", editor, err)
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// previewPolicy validates the edited rules and shows the diff against what is on the server now.
+// edit goes back to editing, done is called once the preview is left any other way.
+func (vwr *Viewer) previewPolicy(vi *backend.VaultInstance, name string, rules string, edit func(), done func()) {
+	if err := backend.ValidatePolicy(name, rules); err != nil {
+		prompt.Notify(vwr.prompter, "Invalid policy", err.Error())
+		edit()
+		return
+	}
+
+	client := *vi
+	go func() {
+		server, err := client.ServerPolicy(name)
+		vwr.app.QueueUpdateDraw(func() {
+			if err != nil {
+				prompt.Notify(vwr.prompter, "Unable to read "+name, err.Error())
+				edit()
+				return
+			}
+			vwr.showPreview(vi, name, server, rules, edit, done)
+		})
+	}()
+}
+
+func (vwr *Viewer) showPreview(vi *backend.VaultInstance, name string, server string, rules string, edit func(), done func()) {
+	diff := backend.UnifiedDiff("server/"+name, "edited/"+name, server, rules)
+	view := tview.NewTextView().SetDynamicColors(true).SetText(colorDiff(diff))
+	if len(diff) == 0 {
+		view.SetText("No changes against the server")
+	}
+
+	form := tview.NewForm()
+	closePreview := func() {
+		vwr.pages.RemovePage(previewPage)
+	}
+	form.AddButton("Save", func() {
+		if vi.ReadOnly() {
+			prompt.Notify(vwr.prompter, "Not saved", backend.ErrReadOnly.Error())
+			return
+		}
+		closePreview()
+		vwr.pages.RemovePage(editorPage)
+		done()
+		vwr.savePolicy(vi, name, rules)
+	})
+	form.AddButton("Edit", func() {
+		closePreview()
+		edit()
+	})
+	form.AddButton("Cancel", func() {
+		closePreview()
+		vwr.pages.RemovePage(editorPage)
+		done()
+	})
+	form.SetCancelFunc(func() {
+		closePreview()
+		edit()
+	})
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(view, 0, 1, false).
+		AddItem(form, 3, 0, true)
+	title := fmt.Sprintf("Save policy %s on %s?", name, vi.DisplayName)
+	if len(server) == 0 {
+		title = fmt.Sprintf("Create policy %s on %s?", name, vi.DisplayName)
+	}
+	layout.SetBorder(true).SetTitle(title)
+	vwr.pages.AddPage(previewPage, layout, true, true)
+	vwr.app.SetFocus(form)
+}
+
+// colorDiff shows added lines in green and removed ones in red
+func colorDiff(diff string) string {
+	out := []string{}
+	for _, line := range strings.Split(diff, "\n") {
+		escaped := tview.Escape(line)
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"), strings.HasPrefix(line, "@@"):
+			out = append(out, "[yellow]"+escaped+"[-]")
+		case strings.HasPrefix(line, "+"):
+			out = append(out, "[green]"+escaped+"[-]")
+		case strings.HasPrefix(line, "-"):
+			out = append(out, "[red]"+escaped+"[-]")
+		default:
+			out = append(out, escaped)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func (vwr *Viewer) savePolicy(vi *backend.VaultInstance, name string, rules string) {
+	client := *vi
+	go func() {
+		if err := client.WritePolicy(name, rules); err != nil {
+			prompt.Notify(vwr.prompter, "Unable to save "+name, err.Error())
+			return
+		}
+		prompt.Notify(vwr.prompter, "Saved", fmt.Sprintf("Policy %s was written to %s", name, client.DisplayName))
+		vwr.reloadInstance(vi, client)
+	}()
+}
+
+// confirmDelete only deletes once the policy name has been typed in
+func (vwr *Viewer) confirmDelete(ref *TNodeRef) {
+	vi := vwr.editableInstance(ref)
+	if vi == nil || ref.Type != 8 {
+		return
+	}
+	if vi.ReadOnly() {
+		prompt.Notify(vwr.prompter, "Not deleted", backend.ErrReadOnly.Error())
+		return
+	}
+	name := ref.Policy.Name
+
+	previous := vwr.app.GetFocus()
+	input := tview.NewInputField().SetLabel("Type " + name + " to delete: ")
+	input.SetBorder(true).SetTitle("Delete policy " + name + " from " + vi.DisplayName)
+	input.SetDoneFunc(func(key tcell.Key) {
+		vwr.pages.RemovePage(namePage)
+		vwr.app.SetFocus(previous)
+		if key != tcell.KeyEnter {
+			return
+		}
+		if input.GetText() != name {
+			vwr.showText("The name did not match, nothing was deleted")
+			return
+		}
+		client := *vi
+		go func() {
+			if err := client.DeletePolicy(name); err != nil {
+				prompt.Notify(vwr.prompter, "Unable to delete "+name, err.Error())
+				return
+			}
+			prompt.Notify(vwr.prompter, "Deleted", fmt.Sprintf("Policy %s was deleted from %s", name, client.DisplayName))
+			vwr.reloadInstance(vi, client)
+		}()
+	})
+	vwr.pages.AddPage(namePage, modal(input, 70, 3), true, true)
+	vwr.app.SetFocus(input)
+}
+
+// reloadInstance fetches the ACL and policies again after a change and rebuilds the instance's branch.
+// It runs on a background goroutine with client as the copy of vi to work with.
+func (vwr *Viewer) reloadInstance(vi *backend.VaultInstance, client backend.VaultInstance) {
+	update, err := client.Reload()
+	vwr.app.QueueUpdateDraw(func() {
+		if err != nil {
+			vwr.showText(fmt.Sprintf("%s: %v", vi.DisplayName, err))
+			return
+		}
+		// the token may have been renewed in the meantime
+		update.Token = vi.Token
+		update.State = vi.State
+		*vi = update
+		vwr.rebuildInstance(vi)
+	})
+}
@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/fennysoftware/vaultviewer/internal/backend"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const lintPage = "lint"
+
+var severityColors = map[backend.Severity]tcell.Color{
+	backend.SeverityLow:    tcell.ColorWhite,
+	backend.SeverityMedium: tcell.ColorOrange,
+	backend.SeverityHigh:   tcell.ColorRed,
+}
+
+// showFindings lints the policies and ACL of the selected instance, Esc closes the panel
+func (vwr *Viewer) showFindings(ref *TNodeRef) {
+	if ref == nil || ref.Instance == nil || ref.Instance.State != backend.StateACLLoaded {
+		vwr.showText("Select a connected instance to lint its policies")
+		return
+	}
+	vi := ref.Instance
+	findings := backend.Lint(vi.Policies, vi.Acl, vwr.lint)
+
+	previous := vwr.app.GetFocus()
+	table := tview.NewTable().SetFixed(1, 0).SetSelectable(true, false)
+	title := fmt.Sprintf("Findings for %s: %d (Esc to close)", vi.DisplayName, len(findings))
+	if vi.PoliciesErr != nil {
+		title = fmt.Sprintf("Findings for %s: %d, resultant ACL only (Esc to close)", vi.DisplayName, len(findings))
+	}
+	table.SetBorder(true).SetTitle(title)
+
+	for col, text := range []string{"Severity", "Rule", "Policy", "Path", "Finding"} {
+		table.SetCell(0, col, tview.NewTableCell(text).SetSelectable(false).SetTextColor(tcell.ColorYellow))
+	}
+	for i, f := range findings {
+		row := i + 1
+		col := severityColors[f.Severity]
+		table.SetCell(row, 0, tview.NewTableCell(f.Level).SetTextColor(col))
+		table.SetCell(row, 1, tview.NewTableCell(f.RuleID))
+		table.SetCell(row, 2, tview.NewTableCell(tview.Escape(f.Policy)))
+		table.SetCell(row, 3, tview.NewTableCell(tview.Escape(f.Path)))
+		table.SetCell(row, 4, tview.NewTableCell(tview.Escape(f.Message)).SetExpansion(1))
+	}
+	if len(findings) == 0 {
+		table.SetCell(1, 4, tview.NewTableCell("no findings"))
+	}
+
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			vwr.pages.RemovePage(lintPage)
+			vwr.app.SetFocus(previous)
+		}
+	})
+	vwr.pages.AddPage(lintPage, table, true, true)
+	vwr.app.SetFocus(table)
+	vwr.showText(backend.FindingsText(vi.DisplayName, findings))
+}
@@ -0,0 +1,165 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fennysoftware/vaultviewer/internal/backend"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	matrixPage = "matrix"
+	exportPage = "export"
+)
+
+// matrix columns before the capabilities
+var matrixColumns = []string{"Path", "Rule"}
+
+// capabilityMatrix is the table of every rule of an instance against every capability
+type capabilityMatrix struct {
+	vwr     *Viewer
+	vi      *backend.VaultInstance
+	rows    []backend.MatrixRow
+	shown   []backend.MatrixRow
+	sortCol int
+	filter  *tview.InputField
+	table   *tview.Table
+	layout  *tview.Flex
+}
+
+// showMatrix opens the capability matrix of the selected instance.
+// s sorts by the next column, / filters, w writes a CSV file and Esc closes.
+func (vwr *Viewer) showMatrix(ref *TNodeRef) {
+	if ref == nil || ref.Instance == nil || ref.Instance.State != backend.StateACLLoaded {
+		vwr.showText("Select a connected instance or sandbox to see its capability matrix")
+		return
+	}
+	previous := vwr.app.GetFocus()
+	m := &capabilityMatrix{vwr: vwr, vi: ref.Instance, rows: ref.Instance.Acl.Matrix()}
+
+	m.filter = tview.NewInputField().SetLabel("Filter (path prefixes and capabilities): ")
+	m.filter.SetChangedFunc(func(string) {
+		m.refresh()
+	})
+	m.filter.SetDoneFunc(func(tcell.Key) {
+		vwr.app.SetFocus(m.table)
+	})
+
+	m.table = tview.NewTable().SetFixed(1, 1).SetSelectable(true, false)
+	m.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			vwr.pages.RemovePage(matrixPage)
+			vwr.app.SetFocus(previous)
+			return nil
+		}
+		switch event.Rune() {
+		case 's':
+			m.sortCol = (m.sortCol + 1) % (len(matrixColumns) + len(backend.MatrixCapabilities))
+			m.refresh()
+			return nil
+		case '/':
+			vwr.app.SetFocus(m.filter)
+			return nil
+		case 'w':
+			m.export()
+			return nil
+		}
+		return event
+	})
+
+	m.layout = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(m.filter, 1, 0, false).
+		AddItem(m.table, 0, 1, true)
+	m.layout.SetBorder(true)
+	m.refresh()
+	vwr.pages.AddPage(matrixPage, m.layout, true, true)
+	vwr.app.SetFocus(m.table)
+}
+
+// refresh filters, sorts and redraws the table
+func (m *capabilityMatrix) refresh() {
+	m.shown = backend.FilterMatrix(m.rows, m.filter.GetText())
+	col := m.sortCol
+	sort.SliceStable(m.shown, func(i, j int) bool {
+		a, b := m.shown[i], m.shown[j]
+		switch {
+		case col == 0:
+			return a.Path < b.Path
+		case col == 1:
+			if a.RuleType != b.RuleType {
+				return a.RuleType < b.RuleType
+			}
+		default:
+			c := backend.MatrixCapabilities[col-len(matrixColumns)]
+			if a.Has(c) != b.Has(c) {
+				return a.Has(c)
+			}
+		}
+		return a.Path < b.Path
+	})
+
+	m.table.Clear()
+	headers := append(append([]string{}, matrixColumns...), backend.MatrixCapabilities...)
+	for i, h := range headers {
+		if i == col {
+			h += " ▼"
+		}
+		m.table.SetCell(0, i, tview.NewTableCell(h).SetSelectable(false).SetTextColor(tcell.ColorYellow))
+	}
+	for r, row := range m.shown {
+		m.table.SetCell(r+1, 0, tview.NewTableCell(tview.Escape(row.Path)))
+		m.table.SetCell(r+1, 1, tview.NewTableCell(row.RuleType))
+		for i, c := range backend.MatrixCapabilities {
+			cell := tview.NewTableCell(" ").SetAlign(tview.AlignCenter)
+			if row.Has(c) {
+				cell.SetText("✔").SetBackgroundColor(tcell.ColorDarkGreen)
+				if c == backend.DenyCapability {
+					cell.SetText("✘").SetBackgroundColor(tcell.ColorDarkRed)
+				} else if c == backend.SudoCapability {
+					cell.SetBackgroundColor(tcell.ColorOrange)
+				}
+			}
+			m.table.SetCell(r+1, len(matrixColumns)+i, cell)
+		}
+	}
+	m.layout.SetTitle(fmt.Sprintf("Capabilities of %s: %d of %d rules (s sort, / filter, w CSV, Esc close)", m.vi.DisplayName, len(m.shown), len(m.rows)))
+}
+
+// export asks for a file name and writes the rows that are shown
+func (m *capabilityMatrix) export() {
+	vwr := m.vwr
+	name := strings.NewReplacer("/", "_", " ", "_", ":", "").Replace(m.vi.DisplayName)
+	input := tview.NewInputField().SetLabel("CSV file: ").SetText(name + "-capabilities.csv")
+	input.SetBorder(true).SetTitle("Export the capability matrix")
+	input.SetDoneFunc(func(key tcell.Key) {
+		vwr.pages.RemovePage(exportPage)
+		vwr.app.SetFocus(m.table)
+		path := strings.TrimSpace(input.GetText())
+		if key != tcell.KeyEnter || len(path) == 0 {
+			return
+		}
+		if err := writeMatrix(path, m.shown); err != nil {
+			vwr.showText(fmt.Sprintf("Unable to export: %v", err))
+			return
+		}
+		vwr.showText(fmt.Sprintf("%d rules written to %s", len(m.shown), path))
+	})
+	vwr.pages.AddPage(exportPage, modal(input, 70, 3), true, true)
+	vwr.app.SetFocus(input)
+}
+
+func writeMatrix(path string, rows []backend.MatrixRow) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := backend.WriteMatrixCSV(f, rows); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"sync"
+
+	"github.com/fennysoftware/vaultviewer/internal/prompt"
+	"github.com/rivo/tview"
+)
+
+const (
+	promptPage = "prompt"
+	noticePage = "notice"
+)
+
+// FormPrompter shows prompts as a modal form on top of the viewer.
+// Ask is called from the connection goroutines and blocks until the form is closed.
+type FormPrompter struct {
+	mu    sync.Mutex
+	app   *tview.Application
+	pages *tview.Pages
+}
+
+func NewFormPrompter(app *tview.Application, pages *tview.Pages) *FormPrompter {
+	return &FormPrompter{app: app, pages: pages}
+}
+
+func (fp *FormPrompter) Ask(title string, fields []prompt.Field) ([]string, error) {
+	// one prompt on screen at a time
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	done := make(chan []string, 1)
+	fp.app.QueueUpdateDraw(func() {
+		previous := fp.app.GetFocus()
+		form := tview.NewForm()
+		for _, f := range fields {
+			if f.Secret {
+				form.AddPasswordField(f.Label, f.Value, 40, '*', nil)
+			} else {
+				form.AddInputField(f.Label, f.Value, 40, nil, nil)
+			}
+		}
+		closeForm := func(answers []string) {
+			fp.pages.RemovePage(promptPage)
+			fp.app.SetFocus(previous)
+			done <- answers
+		}
+		form.AddButton("OK", func() {
+			answers := []string{}
+			for i := range fields {
+				answers = append(answers, form.GetFormItem(i).(*tview.InputField).GetText())
+			}
+			closeForm(answers)
+		})
+		form.AddButton("Cancel", func() {
+			closeForm(nil)
+		})
+		form.SetCancelFunc(func() {
+			closeForm(nil)
+		})
+		form.SetBorder(true).SetTitle(title)
+
+		fp.pages.AddPage(promptPage, modal(form, 60, 2*len(fields)+5), true, true)
+		fp.app.SetFocus(form)
+	})
+
+	answers := <-done
+	if answers == nil {
+		return nil, prompt.ErrCancelled
+	}
+	return answers, nil
+}
+
+// Notify shows a message box, it does not wait for it to be dismissed
+func (fp *FormPrompter) Notify(title string, message string) {
+	fp.app.QueueUpdateDraw(func() {
+		previous := fp.app.GetFocus()
+		notice := tview.NewModal().
+			SetText(title + "\n\n" + tview.Escape(message)).
+			AddButtons([]string{"OK"}).
+			SetDoneFunc(func(int, string) {
+				fp.pages.RemovePage(noticePage)
+				fp.app.SetFocus(previous)
+			})
+		fp.pages.AddPage(noticePage, notice, true, true)
+		fp.app.SetFocus(notice)
+	})
+}
+
+// modal centres p in a box of the given size
+func modal(p tview.Primitive, width, height int) tview.Primitive {
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(p, height, 1, true).
+			AddItem(nil, 0, 1, false), width, 1, true).
+		AddItem(nil, 0, 1, false)
+}
@@ -0,0 +1,135 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fennysoftware/vaultviewer/internal/backend"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const queryPage = "query"
+
+// showQuery asks for a path and shows what the instance's ACL allows on it
+func (vwr *Viewer) showQuery(ref *TNodeRef) {
+	if ref == nil || ref.Instance == nil || ref.Instance.State != backend.StateACLLoaded {
+		vwr.showText("Select a connected instance to query capabilities")
+		return
+	}
+	vi := ref.Instance
+
+	previous := vwr.app.GetFocus()
+	input := tview.NewInputField().SetLabel("Path: ")
+	input.SetBorder(true).SetTitle("Capabilities for " + vi.DisplayName)
+	input.SetDoneFunc(func(key tcell.Key) {
+		vwr.pages.RemovePage(queryPage)
+		vwr.app.SetFocus(previous)
+		if key != tcell.KeyEnter || len(strings.TrimSpace(input.GetText())) == 0 {
+			return
+		}
+		vwr.runQuery(vi, strings.TrimSpace(input.GetText()))
+	})
+	vwr.pages.AddPage(queryPage, modal(input, 70, 3), true, true)
+	vwr.app.SetFocus(input)
+}
+
+// runQuery shows the local evaluation straight away and adds Vault's own answer when it arrives
+func (vwr *Viewer) runQuery(vi *backend.VaultInstance, path string) {
+	ev := vi.Acl.Evaluate(path)
+	text := formatEvaluation(vi.DisplayName, ev)
+	if vi.Offline() {
+		vwr.showText(text + "\n\t\tOffline sandbox, not checked against Vault")
+		return
+	}
+	vwr.showText(text + "\n\t\tsys/capabilities-self: checking...")
+
+	client := *vi
+	go func() {
+		remote, err := client.CapabilitiesSelf(path)
+		vwr.app.QueueUpdateDraw(func() {
+			vwr.showText(text + "\n" + formatCrossCheck(ev, remote, err))
+		})
+	}()
+}
+
+func formatEvaluation(name string, ev backend.Evaluation) string {
+	rule := "no matching rule, nothing is allowed"
+	if ev.RuleType == backend.RuleRoot {
+		rule = "root policy"
+	} else if ev.Rule != nil {
+		rule = fmt.Sprintf("%s (%s)", ev.Rule.Path, ev.RuleType)
+	}
+	detail := ""
+	if ev.Denied {
+		detail = "\n\t\tThe matching rule denies the path"
+		if names := ev.Rule.Permissions.DenyingPolicies(); len(names) > 0 {
+			detail += ", denied by " + strings.Join(names, ", ")
+		}
+	} else if ev.Rule != nil {
+		for _, c := range ev.Capabilities {
+			if names := ev.Rule.Permissions.GrantingPolicies(c); len(names) > 0 {
+				detail += fmt.Sprintf("\n\t\t%s granted by %s", c, strings.Join(names, ", "))
+			}
+		}
+	}
+	return fmt.Sprintf(`
+		Identity			: %s
+		Path				: %s
+		Capabilities		: %s
+		Matching rule		: %s%s
+		`,
+		name,
+		ev.Path,
+		strings.Join(ev.Capabilities, ", "),
+		rule,
+		detail,
+	)
+}
+
+// formatCrossCheck compares the local evaluation with sys/capabilities-self
+func formatCrossCheck(ev backend.Evaluation, remote []string, err error) string {
+	if err != nil {
+		return fmt.Sprintf("\t\tsys/capabilities-self failed: %v", err)
+	}
+	// Vault answers deny for a path no rule matches
+	caps := ev.Capabilities
+	if len(caps) == 0 {
+		caps = []string{backend.DenyCapability}
+	}
+	local := map[string]bool{}
+	for _, c := range caps {
+		local[c] = true
+	}
+	// root tokens report "root" rather than each capability
+	if ev.RuleType == backend.RuleRoot {
+		local["root"] = true
+	}
+
+	missing := []string{}
+	extra := []string{}
+	seen := map[string]bool{}
+	for _, c := range remote {
+		seen[c] = true
+		if !local[c] {
+			missing = append(missing, c)
+		}
+	}
+	for _, c := range caps {
+		if !seen[c] && !(ev.RuleType == backend.RuleRoot && seen["root"]) {
+			extra = append(extra, c)
+		}
+	}
+
+	result := fmt.Sprintf("\t\tsys/capabilities-self: %s\n", strings.Join(remote, ", "))
+	if len(missing) == 0 && len(extra) == 0 {
+		return result + "\t\tThe local evaluation agrees with Vault"
+	}
+	if len(missing) > 0 {
+		result += fmt.Sprintf("\t\tVault also allows: %s\n", strings.Join(missing, ", "))
+	}
+	if len(extra) > 0 {
+		result += fmt.Sprintf("\t\tVault does not allow: %s\n", strings.Join(extra, ", "))
+	}
+	return result
+}
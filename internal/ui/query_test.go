@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fennysoftware/vaultviewer/internal/backend"
+)
+
+func TestFormatCrossCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		ev      backend.Evaluation
+		remote  []string
+		want    string
+		notWant string
+	}{
+		{
+			name:   "agrees",
+			ev:     backend.Evaluation{Capabilities: []string{"read", "list"}, RuleType: backend.RulePrefix},
+			remote: []string{"list", "read"},
+			want:   "agrees with Vault",
+		},
+		{
+			name:   "unmatched path is a deny",
+			ev:     backend.Evaluation{Capabilities: []string{}, RuleType: backend.RuleNone},
+			remote: []string{"deny"},
+			want:   "agrees with Vault",
+		},
+		{
+			name:   "deny rule",
+			ev:     backend.Evaluation{Capabilities: []string{"deny"}, RuleType: backend.RuleExact, Denied: true},
+			remote: []string{"deny"},
+			want:   "agrees with Vault",
+		},
+		{
+			name:   "root",
+			ev:     backend.Evaluation{Capabilities: []string{"create", "read", "update", "patch", "delete", "list", "sudo"}, RuleType: backend.RuleRoot},
+			remote: []string{"root"},
+			want:   "agrees with Vault",
+		},
+		{
+			name:    "unmatched path Vault allows",
+			ev:      backend.Evaluation{Capabilities: []string{}, RuleType: backend.RuleNone},
+			remote:  []string{"read"},
+			want:    "Vault also allows: read",
+			notWant: "also allows: deny",
+		},
+		{
+			name:   "Vault allows less",
+			ev:     backend.Evaluation{Capabilities: []string{"read", "update"}, RuleType: backend.RuleExact},
+			remote: []string{"read"},
+			want:   "Vault does not allow: update",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatCrossCheck(tt.ev, tt.remote, nil)
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("cross-check %q does not contain %q", got, tt.want)
+			}
+			if len(tt.notWant) > 0 && strings.Contains(got, tt.notWant) {
+				t.Errorf("cross-check %q contains %q", got, tt.notWant)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/fennysoftware/vaultviewer/internal/backend"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const sandboxPage = "sandbox"
+
+// AddSandbox loads local policy files into an offline instance at the bottom of the tree
+func (vwr *Viewer) AddSandbox(files []string) error {
+	policies, err := backend.LoadPolicyFiles(files)
+	if err != nil {
+		return err
+	}
+	vwr.addSandboxNode(backend.NewSandbox(sandboxName(files), policies))
+	return nil
+}
+
+func sandboxName(files []string) string {
+	names := []string{}
+	for _, f := range files {
+		names = append(names, filepath.Base(f))
+	}
+	return "sandbox: " + strings.Join(names, ", ")
+}
+
+// openSandbox asks for policy files, on a connected instance they are simulated on top of
+// the token's policies, anywhere else they make a sandbox of their own
+func (vwr *Viewer) openSandbox(ref *TNodeRef) {
+	var vi *backend.VaultInstance
+	title := "Load policy files into a sandbox"
+	if ref != nil && ref.Type == 0 && ref.Instance.State == backend.StateACLLoaded && !ref.Instance.Offline() {
+		vi = ref.Instance
+		title = "Simulate " + vi.DisplayName + " with draft policies"
+	}
+
+	previous := vwr.app.GetFocus()
+	input := tview.NewInputField().SetLabel("Files: ")
+	input.SetBorder(true).SetTitle(title)
+	input.SetDoneFunc(func(key tcell.Key) {
+		vwr.pages.RemovePage(sandboxPage)
+		vwr.app.SetFocus(previous)
+		files := strings.Fields(input.GetText())
+		if key != tcell.KeyEnter || len(files) == 0 {
+			return
+		}
+		if vi == nil {
+			if err := vwr.AddSandbox(files); err != nil {
+				vwr.showText(fmt.Sprintf("Unable to load the sandbox: %v", err))
+			}
+			return
+		}
+		drafts, err := backend.LoadPolicyFiles(files)
+		if err != nil {
+			vwr.showText(fmt.Sprintf("Unable to load the drafts: %v", err))
+			return
+		}
+		sandbox, err := vi.Simulate(drafts)
+		if err != nil {
+			vwr.showText(fmt.Sprintf("Unable to simulate: %v", err))
+			return
+		}
+		vwr.addSandboxNode(sandbox)
+	})
+	vwr.pages.AddPage(sandboxPage, modal(input, 80, 3), true, true)
+	vwr.app.SetFocus(input)
+}
+
+func (vwr *Viewer) addSandboxNode(vi backend.VaultInstance) {
+	tnt := BuildNodeRef(&vi, vi.DisplayName, 0, backend.PathPermissions{})
+	node := tview.NewTreeNode(vi.DisplayName).SetReference(tnt)
+	updateInstanceNode(node, tnt)
+	tnt.Expand(node)
+	vwr.tree.GetRoot().AddChild(node)
+	vwr.tree.SetCurrentNode(node)
+	vwr.ShowInfo(tnt)
+}
@@ -0,0 +1,131 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fennysoftware/vaultviewer/internal/backend"
+	"github.com/rivo/tview"
+)
+
+// tokenLabel describes the token lifetime for the tree and the status line
+func tokenLabel(ti backend.TokenInfo) string {
+	if !ti.Expires() {
+		if ti.Root {
+			return "root token, no expiry"
+		}
+		return "no expiry"
+	}
+
+	label := "ttl " + ti.Remaining().Round(time.Second).String()
+	if ti.Remaining() == 0 {
+		label = "token expired"
+	} else if ti.ExpiringSoon() {
+		label = "EXPIRING in " + ti.Remaining().Round(time.Second).String()
+	}
+	if !ti.Renewable {
+		label += ", non-renewable"
+	}
+	if ti.Root {
+		label = "root, " + label
+	}
+	return label
+}
+
+func hasToken(vi *backend.VaultInstance) bool {
+	return !vi.Offline() && (vi.State == backend.StateAuthenticated || vi.State == backend.StateACLLoaded)
+}
+
+// refreshStatus redraws the instance labels and the status line so the TTLs count down
+func (vwr *Viewer) refreshStatus() {
+	parts := []string{}
+	for _, node := range vwr.tree.GetRoot().GetChildren() {
+		ref, ok := node.GetReference().(*TNodeRef)
+		if ok && ref != nil && ref.Type == 5 {
+			updateGroupNode(node, ref)
+		}
+	}
+	for _, node := range instanceNodes(vwr.tree.GetRoot()) {
+		ref := node.GetReference().(*TNodeRef)
+		updateInstanceNode(node, ref)
+		if !hasToken(ref.Instance) {
+			continue
+		}
+		part := fmt.Sprintf("%s: %s", ref.Displayname, tokenLabel(ref.Instance.Token))
+		if ref.Instance.Token.ExpiringSoon() {
+			part = "[orange]" + tview.Escape(part) + "[-]"
+		} else {
+			part = tview.Escape(part)
+		}
+		parts = append(parts, part)
+	}
+	vwr.status.SetText(strings.Join(parts, " | "))
+}
+
+// tickStatus keeps the TTLs current while the application runs
+func (vwr *Viewer) tickStatus() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		vwr.app.QueueUpdateDraw(vwr.refreshStatus)
+	}
+}
+
+// watchToken replaces any running token watcher for the instance, updates it queued before
+// it was stopped are dropped so they can't overwrite a reconnected instance
+func (vwr *Viewer) watchToken(node *tview.TreeNode, ref *TNodeRef) {
+	vwr.stopWatch(ref)
+	var tw *backend.TokenWatcher
+	current := func() bool {
+		return vwr.watchers[ref] == tw
+	}
+	tw = ref.Instance.WatchToken(func(ti backend.TokenInfo, err error) {
+		vwr.app.QueueUpdateDraw(func() {
+			if !current() {
+				return
+			}
+			ref.Instance.Token = ti
+			if err != nil {
+				ref.Instance.State = backend.StateFailed
+				ref.Instance.Err = err
+			}
+			updateInstanceNode(node, ref)
+		})
+	}, func(vi backend.VaultInstance) {
+		vwr.app.QueueUpdateDraw(func() {
+			if !current() {
+				return
+			}
+			*ref.Instance = vi
+			vwr.rebuildInstance(ref.Instance)
+		})
+	})
+	vwr.watchers[ref] = tw
+}
+
+func (vwr *Viewer) stopWatch(ref *TNodeRef) {
+	if tw, ok := vwr.watchers[ref]; ok {
+		tw.Stop()
+		delete(vwr.watchers, ref)
+	}
+}
+
+// rebuildInstance redraws the node of an instance whose token, ACL or policies were replaced,
+// expanded branches are built again from the new data
+func (vwr *Viewer) rebuildInstance(vi *backend.VaultInstance) {
+	for _, node := range instanceNodes(vwr.tree.GetRoot()) {
+		ref := node.GetReference().(*TNodeRef)
+		if ref.Instance != vi {
+			continue
+		}
+		if len(node.GetChildren()) > 0 {
+			node.ClearChildren()
+			ref.Expand(node)
+		}
+		if vwr.tree.GetCurrentNode() == node {
+			vwr.ShowInfo(ref)
+		}
+	}
+	vwr.refreshStatus()
+}
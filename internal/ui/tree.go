@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
 
 	backend "github.com/fennysoftware/vaultviewer/internal/backend"
 	config "github.com/fennysoftware/vaultviewer/internal/config"
@@ -18,23 +20,39 @@ type TNodeRef struct {
 	Type        int
 	PP          backend.PathPermissions
 	Instance    *backend.VaultInstance
+	// for identities of an instance with profiles, the group whose Instance is the active identity
+	Group *TNodeRef
+	// for policy nodes
+	Policy *backend.Policy
 }
 
 func (tn *TNodeRef) GetInfo() string {
 
 	if tn.Type == 4 {
 		config := struct {
-			Displayname string `json:"Displayname"`
-			Token       string `json:"Token"`
-			Namespace   string `json:"Namespace"`
-			Address     string `json:"Address"`
-			IsRoot      bool   `json:"IsRoot"`
+			Displayname      string   `json:"Displayname"`
+			Token            string   `json:"Token"`
+			Namespace        string   `json:"Namespace"`
+			Address          string   `json:"Address"`
+			IsRoot           bool     `json:"IsRoot"`
+			AuthMethod       string   `json:"AuthMethod"`
+			Accessor         string   `json:"Accessor"`
+			Policies         []string `json:"Policies"`
+			IdentityPolicies []string `json:"IdentityPolicies"`
+			Renewable        bool     `json:"Renewable"`
+			TTL              string   `json:"TTL"`
 		}{
-			Displayname: tn.Displayname,
-			Token:       tn.Instance.Client.Token(),
-			Namespace:   tn.Instance.Client.Namespace(),
-			Address:     tn.Instance.Client.Address(),
-			IsRoot:      tn.Instance.Acl.Root,
+			Displayname:      tn.Displayname,
+			Token:            tn.Instance.Client.Token(),
+			Namespace:        tn.Instance.Client.Namespace(),
+			Address:          tn.Instance.Client.Address(),
+			IsRoot:           tn.Instance.Acl.Root,
+			AuthMethod:       tn.Instance.AuthMethod,
+			Accessor:         tn.Instance.Token.Accessor,
+			Policies:         tn.Instance.Token.Policies,
+			IdentityPolicies: tn.Instance.Token.IdentityPolicies,
+			Renewable:        tn.Instance.Token.Renewable,
+			TTL:              tokenLabel(tn.Instance.Token),
 		}
 
 		data, err := json.MarshalIndent(&config, "", "\t")
@@ -42,16 +60,97 @@ func (tn *TNodeRef) GetInfo() string {
 			log.Fatal(err)
 		}
 		return string(data)
+	} else if tn.Type == 0 {
+		reason := ""
+		if tn.Instance.InsecureTLS() {
+			reason = "WARNING: TLS certificate verification is disabled\n\n\t\t"
+		}
+		if tn.Instance.State == backend.StateFailed {
+			reason += fmt.Sprintf("%v\n\n\t\tPress Enter to retry", tn.Instance.Err)
+		}
+		if len(tn.Instance.Unresolved) > 0 {
+			reason += "Left out, their templates don't resolve for the entity:\n\t\t" + strings.Join(tn.Instance.Unresolved, "\n\t\t")
+		}
+		return fmt.Sprintf(`
+		Displayname			: %s
+		Address				: %s
+		Namespace			: %s
+		State				: %s
+		%s
+		`,
+			tn.Displayname,
+			tn.Instance.Config.Address,
+			tn.Instance.Config.Namespace,
+			tn.Instance.State,
+			reason,
+		)
+	} else if tn.Type == 7 {
+		if tn.Instance.PoliciesErr != nil {
+			return fmt.Sprintf("%v", tn.Instance.PoliciesErr)
+		}
+		return fmt.Sprintf(`
+		Policies			: %d
+		Token policies		: %s
+		Identity policies	: %s
+		`,
+			len(tn.Instance.Policies),
+			strings.Join(tn.Instance.Token.Policies, ", "),
+			strings.Join(tn.Instance.Token.IdentityPolicies, ", "),
+		)
+	} else if tn.Type == 8 {
+		header := fmt.Sprintf("# policy %s", tn.Policy.Name)
+		if tn.Instance.InEffect(tn.Policy.Name) {
+			header += " (in effect for the current token)"
+		}
+		if tn.Policy.Err != nil {
+			header += fmt.Sprintf("\n# %v", tn.Policy.Err)
+		}
+		if tn.Policy.Templated {
+			header += "\n" + formatTemplated(tn.Instance, tn.Policy)
+		}
+		return header + "\n\n" + tn.Policy.Raw
+	} else if tn.Type == 10 {
+		if tn.Instance.SentinelErr != nil {
+			return fmt.Sprintf("Sentinel policies are not available:\n\n%v", tn.Instance.SentinelErr)
+		}
+		return fmt.Sprintf("%d Sentinel policies", len(tn.Instance.SentinelPolicies))
+	} else if tn.Type == 11 {
+		p := tn.Policy
+		header := fmt.Sprintf("# %s %s\n# enforcement level: %s", strings.ToUpper(p.Type.String()), p.Name, p.EnforcementLevel)
+		if p.Type == backend.PolicyTypeEGP {
+			header += "\n# paths: " + strings.Join(p.SentinelPaths, ", ")
+		}
+		if p.Err != nil {
+			header += fmt.Sprintf("\n# %v", p.Err)
+		}
+		return header + "\n\n" + p.Raw
+	} else if tn.Type == 9 {
+		return formatAttribution(tn)
+	} else if tn.Type == 5 {
+		return fmt.Sprintf(`
+		Instance			: %s
+		Address				: %s
+		Active identity		: %s
+		
+		Press a on an identity to make it the active one,
+		the keys pressed here act as the active identity
+		`,
+			tn.Displayname,
+			tn.Instance.Config.Address,
+			tn.Instance.Config.Identity,
+		)
 	} else {
 		return fmt.Sprintf(`
 		Displayname			: %s
+		Identity			: %s
 		Node Type			: %d
 		Instance 			: %s
 		Path Permissions	: %s
 		`,
 			tn.Displayname,
+			tn.Instance.DisplayName,
 			tn.Type,
-			tn.Instance.Client.Address(),
+			instanceAddress(tn.Instance),
 			tn.PP.Path,
 		)
 	}
@@ -65,16 +164,108 @@ func GetTree(vic config.VaultInstanceConfig) *tview.TreeView {
 	return tree
 }
 
+// the instances are added unconnected, the viewer connects them in the background
+// instances with profiles get a group node with one child per identity, the first identity starts active
 func populateRootNode(vic config.VaultInstanceConfig, root *tview.TreeNode) {
 	for _, vconfig := range vic.Instances {
-		vi, err := backend.BuildAndConnect(vconfig)
-		if err != nil {
-			log.Printf("unable to initialize Vault client: %v", err)
+		target := root
+		var group *TNodeRef
+		if len(vconfig.Profiles) > 0 {
+			// named like the instances are when the config has no name
+			name := vconfig.Name
+			if len(name) == 0 {
+				name = vconfig.Address + " - " + vconfig.Namespace
+			}
+			group = BuildNodeRef(nil, name, 5, backend.PathPermissions{})
+			target = tview.NewTreeNode(name).SetReference(group).SetColor(tcell.ColorGreen)
+			root.AddChild(target)
+		}
+
+		for _, identity := range vconfig.Identities() {
+			vi := backend.NewVaultInstance(identity)
+			tnt := BuildNodeRef(&vi, vi.DisplayName, 0, backend.PathPermissions{})
+			tnt.Group = group
+			if group != nil && group.Instance == nil {
+				group.Instance = tnt.Instance
+			}
+			vi_node := tview.NewTreeNode(vi.DisplayName).SetReference(tnt)
+			updateInstanceNode(vi_node, tnt)
+			target.AddChild(vi_node)
+		}
+		if group != nil {
+			updateGroupNode(target, group)
+		}
+	}
+}
+
+// instanceNodes walks the tree for the nodes of every connection, including identities
+func instanceNodes(root *tview.TreeNode) []*tview.TreeNode {
+	nodes := []*tview.TreeNode{}
+	for _, node := range root.GetChildren() {
+		ref, ok := node.GetReference().(*TNodeRef)
+		if !ok || ref == nil {
 			continue
 		}
-		tnt := BuildNodeRef(&vi, vi.DisplayName, 0, backend.PathPermissions{})
-		vi_node := tview.NewTreeNode(vi.DisplayName).SetColor(tcell.ColorGreen).SetReference(tnt)
-		root.AddChild(vi_node)
+		switch ref.Type {
+		case 0:
+			nodes = append(nodes, node)
+		case 5:
+			nodes = append(nodes, instanceNodes(node)...)
+		}
+	}
+	return nodes
+}
+
+// updateGroupNode shows which identity is active
+func updateGroupNode(node *tview.TreeNode, group *TNodeRef) {
+	node.SetText(fmt.Sprintf("%s (active: %s)", group.Displayname, group.Instance.Config.Identity))
+}
+
+// isActive is true for identities that are the active one of their group
+func (tnt *TNodeRef) isActive() bool {
+	return tnt.Group != nil && tnt.Group.Instance == tnt.Instance
+}
+
+// instanceAddress is where the instance connects to, sandboxes have no address
+func instanceAddress(vi *backend.VaultInstance) string {
+	if vi.Offline() {
+		return "(offline sandbox)"
+	}
+	if vi.Client == nil {
+		return vi.Config.Address
+	}
+	return vi.Client.Address()
+}
+
+// updateInstanceNode refreshes the label and colour of an instance node from its connection state
+func updateInstanceNode(node *tview.TreeNode, tnt *TNodeRef) {
+	tnt.Displayname = tnt.Instance.DisplayName
+	text := fmt.Sprintf("%s (%s)", tnt.Displayname, tnt.Instance.State)
+	if hasToken(tnt.Instance) {
+		text = fmt.Sprintf("%s (%s, %s)", tnt.Displayname, tnt.Instance.State, tokenLabel(tnt.Instance.Token))
+	} else if tnt.Instance.Offline() && tnt.Instance.State == backend.StateACLLoaded {
+		text = fmt.Sprintf("%s (offline)", tnt.Displayname)
+	}
+	if tnt.Instance.InsecureTLS() {
+		text = "!! INSECURE TLS !! " + text
+	}
+	if tnt.isActive() {
+		text = "* " + text
+	}
+	node.SetText(text)
+	switch tnt.Instance.State {
+	case backend.StateConnecting:
+		node.SetColor(tcell.ColorYellow)
+	case backend.StateAuthenticated:
+		node.SetColor(tcell.ColorBlue)
+	case backend.StateACLLoaded:
+		if tnt.Instance.Token.ExpiringSoon() {
+			node.SetColor(tcell.ColorOrange)
+		} else {
+			node.SetColor(tcell.ColorGreen)
+		}
+	case backend.StateFailed:
+		node.SetColor(tcell.ColorRed)
 	}
 }
 
@@ -83,6 +274,13 @@ func populateRootNode(vic config.VaultInstanceConfig, root *tview.TreeNode) {
 // 2 = glob
 // 3 = has capabilities
 // 4 = connection
+// 5 = instance with several identities
+// 6 = segment wildcard
+// 7 = policies
+// 8 = policy
+// 9 = capability of a path
+// 10 = sentinel policies
+// 11 = sentinel policy
 func BuildNodeRef(vi *backend.VaultInstance, name string, ntype int, pp backend.PathPermissions) *TNodeRef {
 	tnt := TNodeRef{}
 	tnt.Type = ntype
@@ -117,10 +315,78 @@ func addACLRoot(tnt *TNodeRef, children []*tview.TreeNode) []*tview.TreeNode {
 	// add nodes to array
 	perms := addACLNodes(tnt)
 	addNodes(aclnode, perms)
+	if len(tnt.Instance.Acl.ParseErrors) > 0 {
+		enode := tview.NewTreeNode(fmt.Sprintf("Parse errors (%d)", len(tnt.Instance.Acl.ParseErrors))).SetColor(tcell.ColorRed)
+		for _, err := range tnt.Instance.Acl.ParseErrors {
+			enode.AddChild(infoNode(err.Error(), tcell.ColorRed))
+		}
+		aclnode.AddChild(enode)
+	}
 	children = append(children, aclnode)
 	return children
 }
 
+// addPoliciesRoot lists the policies of the instance, they are only shown when they could be listed
+func addPoliciesRoot(tnt *TNodeRef, children []*tview.TreeNode) []*tview.TreeNode {
+	ref := BuildNodeRef(tnt.Instance, "Policies", 7, backend.PathPermissions{})
+	if tnt.Instance.PoliciesErr != nil {
+		ref.Displayname = "Policies (unavailable)"
+		return addAppendNewNodeRef(ref, children, true, tcell.ColorRed)
+	}
+	return addAppendNewNodeRef(ref, children, true, tcell.ColorWhite)
+}
+
+// addSentinelRoot lists the RGPs and EGPs, with a not available node on OSS servers
+func addSentinelRoot(tnt *TNodeRef, children []*tview.TreeNode) []*tview.TreeNode {
+	ref := BuildNodeRef(tnt.Instance, "Sentinel policies", 10, backend.PathPermissions{})
+	node := tview.NewTreeNode(ref.Displayname).SetReference(ref).SetSelectable(true)
+	if tnt.Instance.SentinelErr != nil {
+		node.SetText("Sentinel policies (not available)").SetColor(tcell.ColorGray)
+		return append(children, node)
+	}
+	for _, ptype := range []backend.PolicyType{backend.PolicyTypeRGP, backend.PolicyTypeEGP} {
+		tnode := tview.NewTreeNode(strings.ToUpper(ptype.String()) + "s").SetReference(ref).SetColor(tcell.ColorWhite)
+		for _, p := range tnt.Instance.SentinelPolicies {
+			if p.Type != ptype {
+				continue
+			}
+			pref := BuildNodeRef(tnt.Instance, fmt.Sprintf("%s (%s)", p.Name, p.EnforcementLevel), 11, backend.PathPermissions{})
+			pref.Policy = p
+			col := tcell.ColorWhite
+			if p.Err != nil {
+				col = tcell.ColorRed
+			}
+			tnode.AddChild(tview.NewTreeNode(pref.Displayname).SetReference(pref).SetColor(col))
+		}
+		if len(tnode.GetChildren()) == 0 {
+			tnode.AddChild(infoNode("none", tcell.ColorGray))
+		}
+		node.AddChild(tnode)
+	}
+	return append(children, node)
+}
+
+// addPolicyNodes highlights the policies attached to the current token
+func addPolicyNodes(tnt *TNodeRef, children []*tview.TreeNode) []*tview.TreeNode {
+	for _, p := range tnt.Instance.Policies {
+		ref := BuildNodeRef(tnt.Instance, p.Name, 8, backend.PathPermissions{})
+		ref.Policy = p
+		col := tcell.ColorWhite
+		if tnt.Instance.InEffect(p.Name) {
+			ref.Displayname = p.Name + " (in effect)"
+			col = tcell.ColorGreen
+		}
+		if p.Templated {
+			ref.Displayname += " (templated)"
+		}
+		if p.Err != nil {
+			col = tcell.ColorRed
+		}
+		children = addAppendNewNodeRef(ref, children, true, col)
+	}
+	return children
+}
+
 func addACLNodes(tnt *TNodeRef) []*tview.TreeNode {
 	// add nodes to array
 	children := []*tview.TreeNode{}
@@ -134,13 +400,22 @@ func addACLNodes(tnt *TNodeRef) []*tview.TreeNode {
 	} else {
 		children = addAppendNewNodeRef(BuildNodeRef(tnt.Instance, "PrefixRules", 2, backend.PathPermissions{}), children, true, tcell.ColorWhite)
 	}
+	if len(tnt.Instance.Acl.SegmentWildcardRules) == 0 {
+		children = addAppendNewNodeRef(BuildNodeRef(tnt.Instance, "SegmentWildcardRules", 6, backend.PathPermissions{}), children, true, tcell.ColorRed)
+	} else {
+		children = addAppendNewNodeRef(BuildNodeRef(tnt.Instance, "SegmentWildcardRules", 6, backend.PathPermissions{}), children, true, tcell.ColorWhite)
+	}
 	return children
 }
 
 func addPermissionNodes(tnt *TNodeRef, pp []backend.PathPermissions, children []*tview.TreeNode) []*tview.TreeNode {
 	for _, v := range pp {
 		if v.Permissions != nil {
-			children = addAppendNewNodeRef(BuildNodeRef(tnt.Instance, v.Path, 3, v), children, true, tcell.ColorGreen)
+			ref := BuildNodeRef(tnt.Instance, v.Path, 3, v)
+			if egps := egpNames(tnt.Instance, ruleKey(tnt, v.Path)); len(egps) > 0 {
+				ref.Displayname += " (EGP: " + egps + ")"
+			}
+			children = addAppendNewNodeRef(ref, children, true, tcell.ColorGreen)
 		} else {
 			children = addAppendNewNodeRef(BuildNodeRef(tnt.Instance, v.Path, 3, v), children, false, tcell.ColorRed)
 		}
@@ -148,17 +423,63 @@ func addPermissionNodes(tnt *TNodeRef, pp []backend.PathPermissions, children []
 	return children
 }
 
+// ruleKey puts the * back on paths of the prefix list so they compare like policy paths
+func ruleKey(parent *TNodeRef, path string) string {
+	if parent.Type == 2 && !strings.HasSuffix(path, "*") {
+		return path + "*"
+	}
+	return path
+}
+
+// egpNames lists the EGPs that also apply to a rule path
+func egpNames(vi *backend.VaultInstance, path string) string {
+	names := []string{}
+	for _, p := range vi.EGPsFor(path) {
+		names = append(names, p.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
 func (tnt *TNodeRef) Expand(target *tview.TreeNode) {
 	children := []*tview.TreeNode{}
 
 	switch tnt.Type {
 	case 0:
-		children = addConnectionNodes(tnt)
+		if tnt.Instance.State != backend.StateACLLoaded {
+			return
+		}
+		if !tnt.Instance.Offline() {
+			children = addConnectionNodes(tnt)
+		}
 		children = addACLRoot(tnt, children)
+		children = addPoliciesRoot(tnt, children)
+		if !tnt.Instance.Offline() {
+			children = addSentinelRoot(tnt, children)
+		}
 	case 1:
 		children = addPermissionNodes(tnt, tnt.Instance.Acl.ExactRules, children)
 	case 2:
 		children = addPermissionNodes(tnt, tnt.Instance.Acl.PrefixRules, children)
+	case 6:
+		children = addPermissionNodes(tnt, tnt.Instance.Acl.SegmentWildcardRules, children)
+	case 7:
+		children = addPolicyNodes(tnt, children)
+	case 8:
+		for _, pr := range tnt.Policy.Paths {
+			ref := BuildNodeRef(tnt.Instance, pr.RulePath(), 3, pr.PathPermissions())
+			col := tcell.ColorGreen
+			path, unresolved := tnt.Instance.ResolveRule(pr)
+			if len(unresolved) > 0 {
+				ref.Displayname += " (unresolved: " + strings.Join(unresolved, ", ") + ")"
+				col = tcell.ColorRed
+			} else if path != pr.RulePath() {
+				ref.Displayname += " => " + path
+			}
+			if egps := egpNames(tnt.Instance, path); len(egps) > 0 {
+				ref.Displayname += " (EGP: " + egps + ")"
+			}
+			children = addAppendNewNodeRef(ref, children, true, col)
+		}
 	case 3:
 		cnode := tview.NewTreeNode("Capabilities").SetReference(tnt).SetSelectable(true)
 		if tnt.PP.Permissions.CapabilitiesBitmap == backend.DenyCapabilityInt || len(tnt.PP.Permissions.Capabilities) == 0 {
@@ -167,10 +488,136 @@ func (tnt *TNodeRef) Expand(target *tview.TreeNode) {
 			cnode.SetColor(tcell.ColorYellow)
 		}
 		for _, cap := range tnt.PP.Permissions.Capabilities {
-			node := tview.NewTreeNode(cap).SetReference(tnt).SetSelectable(false)
+			ref := BuildNodeRef(tnt.Instance, cap, 9, tnt.PP)
+			node := tview.NewTreeNode(cap).SetReference(ref).SetSelectable(true)
 			cnode.AddChild(node)
 		}
 		target.AddChild(cnode)
+		addConstraintNodes(tnt, target)
 	}
 	addNodes(target, children)
 }
+
+// addConstraintNodes shows the parameter, wrapping, MFA and control group constraints of a path
+func addConstraintNodes(tnt *TNodeRef, target *tview.TreeNode) {
+	perms := tnt.PP.Permissions
+	addParameters := func(name string, params map[string][]interface{}) {
+		if len(params) == 0 {
+			return
+		}
+		pnode := tview.NewTreeNode(name).SetColor(tcell.ColorYellow)
+		keys := []string{}
+		for k := range params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			pnode.AddChild(infoNode(k+" = "+joinValues(params[k]), tcell.ColorWhite))
+		}
+		target.AddChild(pnode)
+	}
+	addParameters("Allowed parameters", perms.AllowedParameters)
+	addParameters("Denied parameters", perms.DeniedParameters)
+
+	if len(perms.RequiredParameters) > 0 {
+		target.AddChild(infoNode("Required parameters: "+strings.Join(perms.RequiredParameters, ", "), tcell.ColorYellow))
+	}
+	if perms.MinWrappingTTL != 0 {
+		target.AddChild(infoNode("Min wrapping TTL: "+perms.MinWrappingTTL.String(), tcell.ColorYellow))
+	}
+	if perms.MaxWrappingTTL != 0 {
+		target.AddChild(infoNode("Max wrapping TTL: "+perms.MaxWrappingTTL.String(), tcell.ColorYellow))
+	}
+	if len(perms.MFAMethods) > 0 {
+		target.AddChild(infoNode("MFA methods: "+strings.Join(perms.MFAMethods, ", "), tcell.ColorYellow))
+	}
+	if perms.ControlGroup != nil {
+		cgnode := tview.NewTreeNode("Control group, TTL " + perms.ControlGroup.TTL.String()).SetColor(tcell.ColorYellow)
+		for _, f := range perms.ControlGroup.Factors {
+			fnode := tview.NewTreeNode("Factor " + f.Name)
+			if f.Identity != nil {
+				fnode.AddChild(infoNode(fmt.Sprintf("Approvals required: %d", f.Identity.ApprovalsRequired), tcell.ColorWhite))
+				if len(f.Identity.GroupNames) > 0 {
+					fnode.AddChild(infoNode("Group names: "+strings.Join(f.Identity.GroupNames, ", "), tcell.ColorWhite))
+				}
+				if len(f.Identity.GroupIDs) > 0 {
+					fnode.AddChild(infoNode("Group IDs: "+strings.Join(f.Identity.GroupIDs, ", "), tcell.ColorWhite))
+				}
+			}
+			if len(f.ControlledCapabilities) > 0 {
+				fnode.AddChild(infoNode("Controlled capabilities: "+strings.Join(f.ControlledCapabilities, ", "), tcell.ColorWhite))
+			}
+			cgnode.AddChild(fnode)
+		}
+		target.AddChild(cgnode)
+	}
+}
+
+// formatTemplated lists the templated paths of a policy raw and as resolved for the token's entity
+func formatTemplated(vi *backend.VaultInstance, p *backend.Policy) string {
+	lines := []string{"# templated paths for this identity:"}
+	if vi.Offline() && vi.Entity == nil && vi.EntityErr == nil {
+		lines = append(lines, "#   a sandbox has no entity to resolve them against")
+	} else if vi.EntityErr != nil {
+		lines = append(lines, fmt.Sprintf("#   the entity could not be read: %v", vi.EntityErr))
+	} else if vi.Entity != nil {
+		lines = append(lines, fmt.Sprintf("#   entity %s (%s)", vi.Entity.EntityName, vi.Entity.EntityID))
+	}
+	for _, pr := range p.Paths {
+		if !backend.IsTemplated(pr.Path) {
+			continue
+		}
+		path, unresolved := vi.ResolveRule(pr)
+		if len(unresolved) > 0 {
+			lines = append(lines, fmt.Sprintf("#   %s => UNRESOLVED %s, not granted", pr.RulePath(), strings.Join(unresolved, ", ")))
+		} else {
+			lines = append(lines, fmt.Sprintf("#   %s => %s", pr.RulePath(), path))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatAttribution shows which of the token's policies give a capability on a path
+func formatAttribution(tn *TNodeRef) string {
+	perms := tn.PP.Permissions
+	label := "Granted by"
+	if tn.Displayname == backend.DenyCapability {
+		label = "Denied by "
+	}
+	sources := "unknown, the policies could not be read"
+	if len(perms.GrantingPoliciesMap) > 0 {
+		sources = "-"
+		if names := perms.GrantingPolicies(tn.Displayname); len(names) > 0 {
+			sources = strings.Join(names, ", ")
+		}
+	}
+	return fmt.Sprintf(`
+		Path				: %s
+		Capability			: %s
+		%s			: %s
+		Identity			: %s
+		`,
+		tn.PP.Path,
+		tn.Displayname,
+		label,
+		sources,
+		tn.Instance.DisplayName,
+	)
+}
+
+// infoNode is a plain, unselectable line of text in the tree
+func infoNode(text string, col tcell.Color) *tview.TreeNode {
+	return tview.NewTreeNode(tview.Escape(text)).SetSelectable(false).SetColor(col)
+}
+
+// joinValues lists parameter values, an empty list allows any value
+func joinValues(values []interface{}) string {
+	if len(values) == 0 {
+		return "(any)"
+	}
+	out := []string{}
+	for _, v := range values {
+		out = append(out, fmt.Sprint(v))
+	}
+	return strings.Join(out, ", ")
+}
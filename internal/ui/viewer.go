@@ -1,28 +1,57 @@
 package ui
 
 import (
+	"log"
+
+	"github.com/fennysoftware/vaultviewer/internal/backend"
 	"github.com/fennysoftware/vaultviewer/internal/config"
 	"github.com/fennysoftware/vaultviewer/internal/executer"
+	"github.com/fennysoftware/vaultviewer/internal/prompt"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
 type Viewer struct {
-	tree    *tview.TreeView
-	infobox *tview.TextArea
+	app      *tview.Application
+	pages    *tview.Pages
+	prompter prompt.Prompter
+	tree     *tview.TreeView
+	infobox  *tview.TextArea
+	status   *tview.TextView
+	watchers map[*TNodeRef]*backend.TokenWatcher
+	cache    *backend.SessionCache
+	// the first instance picked for a diff
+	compareFrom *backend.VaultInstance
+	lint        *config.LintConfig
 }
 
-func Get(vic config.VaultInstanceConfig, grid *tview.Grid) *Viewer {
+func Get(vic config.VaultInstanceConfig, grid *tview.Grid, app *tview.Application) *Viewer {
 	vwr := Viewer{}
+	vwr.app = app
+	vwr.pages = tview.NewPages().AddPage("main", grid, true, true)
+	vwr.prompter = NewFormPrompter(app, vwr.pages)
+	vwr.watchers = map[*TNodeRef]*backend.TokenWatcher{}
+	vwr.lint = vic.Lint
+	cache, err := backend.NewSessionCache(vic.SessionCache, vwr.prompter)
+	if err != nil {
+		log.Printf("session cache disabled: %v", err)
+	}
+	vwr.cache = cache
 	vwr.tree = GetTree(vic)
 	vwr.tree.SetSelectedFunc(func(node *tview.TreeNode) {
 		reference := node.GetReference()
 		if reference == nil {
 			return
 		}
+		ref, _ := reference.(*TNodeRef)
+		// a failed instance is retried even when it had loaded children before it failed
+		if ref != nil && ref.Type == 0 && ref.Instance.State == backend.StateFailed {
+			vwr.connect(node, ref)
+			vwr.ShowInfo(ref)
+			return
+		}
 		children := node.GetChildren()
 		if len(children) == 0 {
-			ref := reference.(*TNodeRef)
 			if ref != nil {
 				vwr.ShowInfo(ref)
 				ref.Expand(node)
@@ -43,9 +72,55 @@ func Get(vic config.VaultInstanceConfig, grid *tview.Grid) *Viewer {
 	grid.AddItem(vwr.infobox, 0, 1, 1, 1, 0, 0, false)
 	// Layout for screens wider than 100 cells.
 	grid.AddItem(vwr.tree, 0, 0, 1, 1, 0, 100, true)
+
+	vwr.status = tview.NewTextView().SetDynamicColors(true)
+	grid.SetRows(0, 1)
+	grid.AddItem(vwr.status, 1, 0, 1, 2, 0, 0, false)
 	return &vwr
 }
 
+// Root is the primitive to hand to the application, prompts are layered over the grid
+func (vwr *Viewer) Root() tview.Primitive {
+	return vwr.pages
+}
+
+// ConnectAll starts connecting every instance in the background
+func (vwr *Viewer) ConnectAll() {
+	for _, node := range instanceNodes(vwr.tree.GetRoot()) {
+		vwr.connect(node, node.GetReference().(*TNodeRef))
+	}
+	go vwr.tickStatus()
+}
+
+// connect runs BuildAndConnect in a goroutine, all updates to the node happen on the UI goroutine
+func (vwr *Viewer) connect(node *tview.TreeNode, ref *TNodeRef) {
+	node.ClearChildren()
+	vwr.stopWatch(ref)
+	ref.Instance.State = backend.StateConnecting
+	ref.Instance.Err = nil
+	updateInstanceNode(node, ref)
+
+	vconfig := ref.Instance.Config
+	go func() {
+		vi, _ := backend.BuildAndConnect(vconfig, vwr.prompter, vwr.cache, func(state backend.ConnectionState) {
+			vwr.app.QueueUpdateDraw(func() {
+				ref.Instance.State = state
+				updateInstanceNode(node, ref)
+			})
+		})
+		vwr.app.QueueUpdateDraw(func() {
+			*ref.Instance = vi
+			if vi.State == backend.StateACLLoaded {
+				vwr.watchToken(node, ref)
+			}
+			updateInstanceNode(node, ref)
+			if vwr.tree.GetCurrentNode() == node {
+				vwr.ShowInfo(ref)
+			}
+		})
+	}()
+}
+
 func handleEventWithKey(vwr *Viewer, event *tcell.EventKey) {
 	if event == nil {
 		return
@@ -53,26 +128,92 @@ func handleEventWithKey(vwr *Viewer, event *tcell.EventKey) {
 
 	switch event.Rune() {
 	case 'i':
+		// grouping nodes such as parse errors or parameters have no reference
 		node := vwr.tree.GetCurrentNode()
-		reference := node.GetReference()
-		ref := reference.(*TNodeRef)
-		if ref != nil {
+		ref, _ := node.GetReference().(*TNodeRef)
+		vwr.ShowInfo(ref)
+
+	case 'a':
+		// make the selected identity the active one of its instance
+		node := vwr.tree.GetCurrentNode()
+		ref, ok := node.GetReference().(*TNodeRef)
+		if ok && ref != nil && ref.Type == 0 && ref.Group != nil {
+			ref.Group.Instance = ref.Instance
+			vwr.refreshStatus()
 			vwr.ShowInfo(ref)
-		} else {
-			vwr.ShowInfo(nil)
 		}
 
+	case 'c':
+		// what can the selected identity do on a path
+		ref := vwr.selectedRef()
+		vwr.showQuery(ref)
+
+	case 'd':
+		// compare the effective access of two instances or identities
+		ref := vwr.selectedRef()
+		vwr.markCompare(ref)
+
+	case 'l':
+		// lint the policies of the selected instance
+		ref := vwr.selectedRef()
+		vwr.showFindings(ref)
+
+	case 'e', 'E':
+		// edit the selected policy, E uses $EDITOR
+		ref := vwr.selectedRef()
+		vwr.editPolicy(ref, event.Rune() == 'E')
+
+	case 'n':
+		// create a policy on the selected instance
+		ref := vwr.selectedRef()
+		if vi := vwr.editableInstance(ref); vi != nil {
+			vwr.newPolicy(vi, false)
+		}
+
+	case 'x':
+		// delete the selected policy
+		ref := vwr.selectedRef()
+		vwr.confirmDelete(ref)
+
+	case 'o':
+		// offline sandbox from local policy files
+		ref := vwr.selectedRef()
+		vwr.openSandbox(ref)
+
+	case 'm':
+		// capability matrix of the selected instance
+		ref := vwr.selectedRef()
+		vwr.showMatrix(ref)
+
 	case 'r':
-		node := vwr.tree.GetCurrentNode()
-		reference := node.GetReference()
-		if reference != nil {
-			ref := reference.(*TNodeRef)
-			if ref != nil {
-				// we want to run a new terminal/cmd.exe/bash etc
-				executer.Runner(ref.Instance)
-			}
+		ref := vwr.selectedRef()
+		if ref != nil && ref.Instance != nil && ref.Instance.Client != nil {
+			// we want to run a new terminal/cmd.exe/bash etc
+			executer.Runner(ref.Instance)
+		}
+	}
+}
+
+// selectedRef is the reference of the current node, an instance with profiles acts through its active identity
+func (vwr *Viewer) selectedRef() *TNodeRef {
+	node := vwr.tree.GetCurrentNode()
+	if node == nil {
+		return nil
+	}
+	ref, _ := node.GetReference().(*TNodeRef)
+	if ref == nil || ref.Type != 5 {
+		return ref
+	}
+	for _, child := range node.GetChildren() {
+		if identity, ok := child.GetReference().(*TNodeRef); ok && identity != nil && identity.isActive() {
+			return identity
 		}
 	}
+	return ref
+}
+
+func (vwr *Viewer) showText(text string) {
+	vwr.infobox.SetText(text, false)
 }
 
 func (vwr *Viewer) ShowInfo(ref *TNodeRef) {
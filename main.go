@@ -1,27 +1,97 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
 	"github.com/rivo/tview"
 
+	"github.com/fennysoftware/vaultviewer/internal/backend"
 	"github.com/fennysoftware/vaultviewer/internal/config"
 	"github.com/fennysoftware/vaultviewer/internal/ui"
 )
 
 func main() {
+	wipeCache := flag.Bool("wipe-cache", false, "remove the session cache and exit")
+	diff := flag.Bool("diff", false, "print the ACL differences between the two instances named as arguments and exit")
+	lint := flag.Bool("lint", false, "lint the policies of the instances named as arguments and exit, 1 when there are findings")
+	failOn := flag.String("fail-on", "low", "lowest severity that makes -lint exit with 1: low, medium or high")
+	sandbox := flag.String("sandbox", "", "comma separated policy files to load into an offline sandbox")
+	format := flag.String("format", "text", "report format for -diff and -lint, text or json")
+	logFile := flag.String("log", "vaultviewer.log", "file the TUI logs to, the terminal belongs to the UI while it runs")
+	flag.Parse()
 
 	vic, err := config.LoadConfig("./config.yml")
 	if err != nil {
 		panic(err)
 	}
 
+	if *wipeCache {
+		if err := backend.WipeSessionCache(vic.SessionCache); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("session cache removed")
+		return
+	}
+
+	if *diff {
+		if flag.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "usage: vaultviewer -diff [-format text|json] <left> <right>")
+			os.Exit(2)
+		}
+		if err := runDiff(vic, flag.Arg(0), flag.Arg(1), *format); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *lint {
+		if flag.NArg() == 0 {
+			fmt.Fprintln(os.Stderr, "usage: vaultviewer -lint [-fail-on low|medium|high] [-format text|json] <instance>...")
+			os.Exit(2)
+		}
+		failed, err := runLint(vic, flag.Args(), *failOn, *format)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if failed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// anything written to stderr from here on would garble the screen
+	logTo, err := os.OpenFile(*logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logging disabled: %v\n", err)
+		log.SetOutput(io.Discard)
+	} else {
+		defer logTo.Close()
+		log.SetOutput(logTo)
+	}
+
 	grid := tview.NewGrid().
 		SetRows(0).
 		SetColumns(40, 0).
 		SetBorders(true)
-	ui.Get(vic, grid)
-
 	app := tview.NewApplication()
-	if err := app.SetRoot(grid, true).EnableMouse(true).Run(); err != nil {
+	vwr := ui.Get(vic, grid, app)
+	vwr.ConnectAll()
+	if len(*sandbox) > 0 {
+		if err := vwr.AddSandbox(strings.Split(*sandbox, ",")); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if err := app.SetRoot(vwr.Root(), true).EnableMouse(true).Run(); err != nil {
 		panic(err)
 	}
 }